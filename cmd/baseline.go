@@ -0,0 +1,82 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/danielpickens/lamb/v5/pkg/api"
+)
+
+var (
+	baselineOwner   string
+	baselineReason  string
+	baselineExpires string
+)
+
+func init() {
+	rootCmd.AddCommand(baselineCmd)
+	baselineCmd.AddCommand(baselineGenerateCmd)
+	baselineGenerateCmd.Flags().StringVar(&baselineOwner, "owner", "", "The owner of the accepted findings.")
+	baselineGenerateCmd.Flags().StringVar(&baselineReason, "reason", "", "Why the findings are accepted.")
+	baselineGenerateCmd.Flags().StringVar(&baselineExpires, "expires", "", "The date the entries expire, such as 2024-12-31. Defaults to 90 days from now.")
+	_ = baselineGenerateCmd.MarkFlagRequired("owner")
+	_ = baselineGenerateCmd.MarkFlagRequired("reason")
+}
+
+var baselineCmd = &cobra.Command{
+	Use:   "baseline",
+	Short: "Manages baseline files of accepted findings.",
+	Long:  `Manages baseline files of accepted findings, used with --baseline.`,
+}
+
+var baselineGenerateCmd = &cobra.Command{
+	Use:   "generate [json report or -] ...",
+	Short: "Prints a baseline file that accepts the current findings.",
+	Long:  `Reads the -o json output of detect-files, detect-helm, detect-api-resources or detect-all-in-cluster from files or stdin and prints a baseline file with an entry for every finding, such as lamb detect-files -o json | lamb baseline generate - --owner team --reason "scheduled" > .lamb-baseline.yaml`,
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		expires := baselineExpires
+		if expires == "" {
+			expires = time.Now().AddDate(0, 0, 90).Format(api.BaselineDateFormat)
+		}
+		if _, err := time.Parse(api.BaselineDateFormat, expires); err != nil {
+			fmt.Printf("Error: --expires must be a date like %s, got %s\n", api.BaselineDateFormat, expires)
+			os.Exit(1)
+		}
+
+		var outputs []*api.Output
+		for _, file := range args {
+			report, err := readReport(file)
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			outputs = append(outputs, report.Outputs...)
+		}
+
+		data, err := yaml.Marshal(api.NewBaseline(outputs, baselineOwner, baselineReason, expires))
+		if err != nil {
+			fmt.Println("Error writing baseline:", err)
+			os.Exit(1)
+		}
+		fmt.Print(string(data))
+	},
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"github.com/danielpickens/lamb/v5/pkg/api"
+)
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [old report] [new report]",
+	Short: "Compares two reports and shows the new, fixed and unchanged findings.",
+	Long:  `Compares two -o json reports of detect-files, detect-helm, detect-api-resources or detect-all-in-cluster, such as lamb diff yesterday.json today.json. Findings are matched on their rule, file or release, namespace, name, kind, apiVersion and field, and shown as NEW, FIXED or UNCHANGED in the chosen output format. Only NEW findings affect the exit code. Either report can be - for stdin.`,
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		previous, err := readReport(args[0])
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		current, err := readReport(args[1])
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		apiInstance.Outputs = current.Outputs
+		if current.TargetVersions != nil {
+			apiInstance.TargetVersions = current.TargetVersions
+		}
+		apiInstance.Compare(previous)
+		err = apiInstance.DisplayOutput()
+		if err != nil {
+			fmt.Println("Error Parsing Output:", err)
+			os.Exit(1)
+		}
+		exitCode = apiInstance.GetReturnCode()
+		klog.V(5).Infof("exitCode: %d", exitCode)
+	},
+}
+
+// readReport reads the -o json output of a detect command from a file, or stdin for -
+func readReport(file string) (*api.Instance, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading report: %w", err)
+	}
+	report := &api.Instance{}
+	if err := json.Unmarshal(data, report); err != nil {
+		return nil, fmt.Errorf("error reading report %s, it must be the -o json output of lamb: %w", file, err)
+	}
+	return report, nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+
+	"github.com/danielpickens/lamb/v5/pkg/api"
+	"github.com/danielpickens/lamb/v5/pkg/finder"
+)
+
+var dryRun bool
+
+func init() {
+	rootCmd.AddCommand(fixCmd)
+	fixCmd.PersistentFlags().StringVarP(&directory, "directory", "d", "", "The directory to fix when no file is passed. If blank, defaults to current working dir.")
+	fixCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Print a unified diff of the changes instead of writing them.")
+}
+
+var fixCmd = &cobra.Command{
+	Use:   "fix [file to fix or -]",
+	Short: "Rewrites deprecated apiVersions to their replacements.",
+	Long:  `Rewrites deprecated and removed apiVersions in a file, stdin or directory to their replacement when it is available in the target versions. Comments and key order are preserved, and known schema changes such as Ingress backends and required Deployment selectors are migrated.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 || api.IsFileOrStdin(args[0]) {
+			return nil
+		}
+		return fmt.Errorf("invalid file specified: %s", args[0])
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 0 && args[0] == "-" {
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				fmt.Println("Error reading stdin:", err)
+				os.Exit(1)
+			}
+			fixed, _, err := apiInstance.FixVersions(data)
+			if err != nil {
+				fmt.Println("Error fixing versions:", err)
+				os.Exit(1)
+			}
+			if dryRun {
+				err = printDiff("-", data, fixed)
+			} else {
+				_, err = os.Stdout.Write(fixed)
+			}
+			if err != nil {
+				fmt.Println("Error writing output:", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		if len(args) > 0 {
+			err := fixFile(args[0])
+			if err != nil {
+				fmt.Println("Error fixing file:", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		dir := finder.NewFinder(directory, apiInstance)
+		err := dir.ListFiles()
+		if err != nil {
+			fmt.Println("Error running finder:", err)
+			os.Exit(1)
+		}
+		failed := 0
+		for _, file := range dir.FileList {
+			err := fixFile(file)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error fixing file %s: %s\n", file, err)
+				failed++
+			}
+		}
+		if failed > 0 {
+			fmt.Fprintf(os.Stderr, "%d of %d files could not be fixed\n", failed, len(dir.FileList))
+			os.Exit(1)
+		}
+	},
+}
+
+// fixFile rewrites a single file in place, or prints the diff when --dry-run is set
+func fixFile(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	fixed, outputs, err := apiInstance.FixVersions(data)
+	if err != nil {
+		return err
+	}
+	if len(outputs) < 1 {
+		return nil
+	}
+	if dryRun {
+		return printDiff(file, data, fixed)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(file, fixed, info.Mode())
+	if err != nil {
+		return err
+	}
+	for _, output := range outputs {
+		fmt.Printf("%s:%d: %s %s -> %s\n", file, output.Line, output.APIVersion.Kind, output.APIVersion.Name, output.APIVersion.ReplacementAPI)
+	}
+	return nil
+}
+
+func printDiff(file string, original []byte, fixed []byte) error {
+	diff := difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(original)),
+		B:        difflib.SplitLines(string(fixed)),
+		FromFile: "a/" + file,
+		ToFile:   "b/" + file,
+		Context:  3,
+	}
+	return difflib.WriteUnifiedDiff(os.Stdout, diff)
+}
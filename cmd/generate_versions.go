@@ -0,0 +1,152 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"k8s.io/klog/v2"
+
+	"github.com/danielpickens/lamb/v5/pkg/api"
+)
+
+var (
+	openAPISpecs        map[string]string
+	generateComponent   string
+	mergeVersionsFile   string
+	generatedOutputFile string
+)
+
+func init() {
+	rootCmd.AddCommand(generateVersionsCmd)
+	generateVersionsCmd.PersistentFlags().StringToStringVar(&openAPISpecs, "spec", nil, "A map of release versions to OpenAPI v2 files or directories of OpenAPI v3 files, such as v1.25.0=openapi-v1.25.json. Can be passed multiple times.")
+	generateVersionsCmd.PersistentFlags().StringVar(&generateComponent, "component", "k8s", "The component of the generated versions.")
+	generateVersionsCmd.PersistentFlags().StringVar(&mergeVersionsFile, "merge", "", "An existing versions file to merge the generated versions into. Values already in the file are kept.")
+	generateVersionsCmd.PersistentFlags().StringVar(&generatedOutputFile, "output-file", "", "The file to write the versions file to. If blank, prints to stdout.")
+	_ = generateVersionsCmd.MarkPersistentFlagRequired("spec")
+}
+
+var generateVersionsCmd = &cobra.Command{
+	Use:   "generate-versions",
+	Short: "Generates a versions file from Kubernetes OpenAPI documents.",
+	Long:  `Reads the OpenAPI documents of several Kubernetes releases and infers when each group/version/kind was deprecated and removed, and what replaces it. The result is a versions file that can be used with --additional-versions, optionally merged into an existing one.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var releases []api.ReleaseSpec
+		for version, path := range openAPISpecs {
+			documents, err := readOpenAPIDocuments(path)
+			if err != nil {
+				fmt.Printf("Error reading OpenAPI documents for %s: %v\n", version, err)
+				os.Exit(1)
+			}
+			releases = append(releases, api.ReleaseSpec{Version: version, Documents: documents})
+		}
+		versions, err := api.GenerateVersions(releases, generateComponent)
+		if err != nil {
+			fmt.Println("Error generating versions:", err)
+			os.Exit(1)
+		}
+
+		// validate-versions requires a target version for every component in the file
+		targetVersions := api.GeneratedTargetVersions(releases, generateComponent)
+		versionFile := api.VersionFile{DeprecatedVersions: versions, TargetVersions: targetVersions}
+		if mergeVersionsFile != "" {
+			data, err := os.ReadFile(mergeVersionsFile)
+			if err != nil {
+				fmt.Println("Error reading versions file:", err)
+				os.Exit(1)
+			}
+			versionFile = api.VersionFile{}
+			err = yaml.Unmarshal(data, &versionFile)
+			if err != nil {
+				fmt.Println("Error parsing versions file:", err)
+				os.Exit(1)
+			}
+			versionFile.DeprecatedVersions = api.MergeVersions(versionFile.DeprecatedVersions, versions)
+			// target versions already in the file are kept, like every other value
+			if versionFile.TargetVersions == nil {
+				versionFile.TargetVersions = map[string]string{}
+			}
+			for component, version := range targetVersions {
+				if _, found := versionFile.TargetVersions[component]; !found {
+					versionFile.TargetVersions[component] = version
+				}
+			}
+		}
+		klog.V(2).Infof("generated %d deprecated versions", len(versions))
+
+		var data []byte
+		if outputFormat == "json" {
+			data, err = json.Marshal(versionFile)
+		} else {
+			data, err = yaml.Marshal(versionFile)
+		}
+		if err != nil {
+			fmt.Println("Error encoding versions file:", err)
+			os.Exit(1)
+		}
+		if generatedOutputFile == "" {
+			fmt.Print(string(data))
+			return
+		}
+		err = os.WriteFile(generatedOutputFile, data, 0644)
+		if err != nil {
+			fmt.Println("Error writing versions file:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// readOpenAPIDocuments reads a single OpenAPI document, or every .json file below a directory
+func readOpenAPIDocuments(path string) ([][]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{data}, nil
+	}
+	var documents [][]byte
+	err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(file, ".json") {
+			return nil
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		documents = append(documents, data)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(documents) == 0 {
+		return nil, fmt.Errorf("no .json files found in %s", path)
+	}
+	return documents, nil
+}
@@ -34,24 +34,27 @@ import (
 	"io"
 	"os"
 	"strings"
-	"github.com/rogpeppe/go-internal/semver"
+	"time"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"k8s.io/klog/v2"
+
+	"github.com/danielpickens/lamb/v5/pkg/kube"
 )
 
 var (
 	version                       string
 	versionCommit                 string
 	versionFileData               []byte
-	additionalVersionsFile        string
+	additionalVersionsFiles       []string
 	types						  string
 	typesCommit					  string
 	typesFileData				  []byte
 	additionalTypesFile           string
 	directory                     string
 	outputFormat                  string
+	outputTemplate                string
 	ignoreDeprecations            bool
 	ignoreRemovals                bool
 	ignoreUnavailableReplacements bool
@@ -66,6 +69,23 @@ var (
 	noHeaders                     bool
 	exitCode                      int
 	noFooter                      bool
+	kustomize                     bool
+	helmCharts                    bool
+	helmValues                    []string
+	helmSet                       []string
+	strictVersions                bool
+	versionsURL                   string
+	versionsPublicKeyFile         string
+	versionsCacheDir              string
+	targetPlatforms               map[string]string
+	targetFromCluster             bool
+	probeComponents               bool
+	baselineFile                  string
+	showSuppressed                bool
+	policyFile                    string
+	failOn                        []string
+	rulesFile                     string
+	compareTo                     string
 )
 
 const (
@@ -80,6 +100,11 @@ var outputOptions = []string{
 	"custom",
 	"markdown",
 	"csv",
+	"sarif",
+	"junit",
+	"html",
+	"github",
+	"gitlab-codequality",
 }
 
 func init() {
@@ -88,32 +113,57 @@ func init() {
 	rootCmd.PersistentFlags().BoolVar(&ignoreUnavailableReplacements, "ignore-unavailable-replacements", false, "Ignore the default behavior to exit 4 if deprecated but unavailable apiVersions are found.")
 	rootCmd.PersistentFlags().BoolVarP(&onlyShowRemoved, "only-show-removed", "r", false, "Only display the apiVersions that have been removed in the target version.")
 	rootCmd.PersistentFlags().BoolVarP(&noHeaders, "no-headers", "H", false, "When using the default or custom-column output format, don't print headers (default print headers).")
-	rootCmd.PersistentFlags().StringVarP(&additionalVersionsFile, "additional-versions", "f", "", "Additional deprecated versions file to add to the list. Cannot contain any existing versions")
+	rootCmd.PersistentFlags().StringSliceVarP(&additionalVersionsFiles, "additional-versions", "f", nil, "Additional deprecated versions files, directories of versions files or http(s) URLs, merged into the list in order. Entries can add, override or remove versions with action: add|override|remove. Can be passed multiple times.")
+	rootCmd.PersistentFlags().StringVar(&versionsURL, "versions-url", "", "An http(s) URL of a versions file, merged before any --additional-versions. Downloads are cached and the cached copy is used if the URL cannot be reached.")
+	rootCmd.PersistentFlags().StringVar(&versionsPublicKeyFile, "versions-public-key", "", "An ed25519 public key file. If set, every versions file downloaded from a URL must have a valid detached signature at the same URL with .sig appended.")
+	rootCmd.PersistentFlags().StringVar(&versionsCacheDir, "versions-cache-dir", "", "The directory to cache downloaded versions files in. Defaults to lamb/versions in the user cache directory.")
+	rootCmd.PersistentFlags().BoolVar(&strictVersions, "strict-versions", false, "Validate the additional versions files with the same checks as validate-versions and fail on any problem.")
 	rootCmd.PersistentFlags().StringToStringVarP(&targetVersions, "target-versions", "t", targetVersions, "A map of targetVersions to use. This flag supersedes all defaults in version files.")
+	rootCmd.PersistentFlags().StringToStringVar(&targetPlatforms, "target-platform", nil, "A map of platforms to platform versions to target, such as openshift=4.14 or eks=1.27. Expands into target versions using the platforms in the versions files. --target-versions takes precedence.")
 	rootCmd.PersistentFlags().StringToStringVarP(&targetTypes, "target-types", "T", targetTypes, "A map of targetTypes to use. This flag supersedes all defaults in version files.")
 	rootCmd.PersistentFlags().StringToVarStringVarP(&additionalTypesFile, "additional-types", "f", "", "Additional deprecated api call types file to add to the list. Cannot contain any existing versions")
-	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "normal", "The output format to use. (normal|wide|custom|json|yaml|markdown|csv)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "normal", "The output format to use. (normal|wide|custom|json|yaml|markdown|csv|sarif|junit|html|github|gitlab-codequality|go-template=...|go-template-file=...|jsonpath=...)")
 	rootCmd.PersistentFlags().StringSliceVar(&customColumns, "columns", nil, "A list of columns to print. Mandatory when using --output custom, optional with --output markdown")
 	rootCmd.PersistentFlags().StringSliceVar(&componentsFromUser, "components", nil, "A list of components to run checks for. If nil, will check for all found in versions.")
 	rootCmd.PersistentFlags().BoolVar(&noFooter, "no-footer", false, "Disable footer output")
+	rootCmd.PersistentFlags().BoolVar(&showSuppressed, "show-suppressed", false, "Show the findings suppressed by a lamb.io/ignore annotation or a # lamb:ignore comment, with a STATUS of SUPPRESSED.")
+	rootCmd.PersistentFlags().StringVar(&baselineFile, "baseline", "", "A baseline file of accepted findings, such as .lamb-baseline.yaml. Matching findings are shown as SUPPRESSED and do not affect the exit code. Expired entries fail the run.")
+	rootCmd.PersistentFlags().StringVar(&policyFile, "policy", "", "A policy file mapping findings to a severity (error|warn|info) and an exit code. Replaces the default exit codes and the --ignore-* flags.")
+	rootCmd.PersistentFlags().StringVar(&rulesFile, "rules", "", "A file of CEL rules evaluated against every manifest. Each rule that matches is reported with its message and severity.")
+	rootCmd.PersistentFlags().StringSliceVar(&failOn, "fail-on", nil, "Only fail on these findings, one or more of deprecated|removed|replacement-unavailable|none. A shorthand for a --policy with the default exit codes.")
 
 	rootCmd.AddCommand(detectFilesCmd)
 	detectFilesCmd.PersistentFlags().StringVarP(&directory, "directory", "d", "", "The directory to scan. If blank, defaults to current working dir.")
+	detectFilesCmd.PersistentFlags().BoolVar(&helmCharts, "helm-charts", false, "Render Helm charts found in the directory and scan the output instead of the files inside them.")
+	detectFilesCmd.PersistentFlags().StringSliceVar(&helmValues, "helm-values", []string{}, "Values files used when rendering Helm charts found in the directory. Can be passed multiple times.")
+	detectFilesCmd.PersistentFlags().StringArrayVar(&helmSet, "helm-set", []string{}, "Values used when rendering Helm charts found in the directory, in the form key=value. Can be passed multiple times.")
+	detectFilesCmd.PersistentFlags().BoolVar(&kustomize, "kustomize", false, "Render kustomizations found in the directory and scan the output instead of the files inside them.")
+	detectFilesCmd.PersistentFlags().StringVar(&compareTo, "compare-to", "", "A previous -o json report to compare to. Findings are shown as NEW, FIXED or UNCHANGED and only NEW findings affect the exit code.")
 
 	rootCmd.AddCommand(detectHelmCmd)
 	detectHelmCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", "", "Only detect releases in a specific namespace.")
 	detectHelmCmd.PersistentFlags().StringVar(&kubeContext, "kube-context", "", "The kube context to use. If blank, defaults to current context.")
+	detectHelmCmd.PersistentFlags().BoolVar(&targetFromCluster, "target-from-cluster", false, "Use the version of the cluster as the k8s target version. --target-versions takes precedence.")
+	detectHelmCmd.PersistentFlags().BoolVar(&probeComponents, "probe-components", false, "With --target-from-cluster, also use the versions of components found in the cluster by the component-probes in the versions files.")
+	detectHelmCmd.PersistentFlags().StringVar(&compareTo, "compare-to", "", "A previous -o json report to compare to. Findings are shown as NEW, FIXED or UNCHANGED and only NEW findings affect the exit code.")
 
 	rootCmd.AddCommand(detectApiResourceCmd)
 	detectApiResourceCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", "", "Only detect resources in a specific namespace.")
 	detectApiResourceCmd.PersistentFlags().StringVar(&kubeContext, "kube-context", "", "The kube context to use. If blank, defaults to current context.")
+	detectApiResourceCmd.PersistentFlags().BoolVar(&targetFromCluster, "target-from-cluster", false, "Use the version of the cluster as the k8s target version. --target-versions takes precedence.")
+	detectApiResourceCmd.PersistentFlags().BoolVar(&probeComponents, "probe-components", false, "With --target-from-cluster, also use the versions of components found in the cluster by the component-probes in the versions files.")
+	detectApiResourceCmd.PersistentFlags().StringVar(&compareTo, "compare-to", "", "A previous -o json report to compare to. Findings are shown as NEW, FIXED or UNCHANGED and only NEW findings affect the exit code.")
 
 	rootCmd.AddCommand(detectAllInClusterCmd)
 	detectAllInClusterCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", "", "Only detect resources in a specific namespace.")
 	detectAllInClusterCmd.PersistentFlags().StringVar(&kubeContext, "kube-context", "", "The kube context to use. If blank, defaults to current context.")
+	detectAllInClusterCmd.PersistentFlags().BoolVar(&targetFromCluster, "target-from-cluster", false, "Use the version of the cluster as the k8s target version. --target-versions takes precedence.")
+	detectAllInClusterCmd.PersistentFlags().BoolVar(&probeComponents, "probe-components", false, "With --target-from-cluster, also use the versions of components found in the cluster by the component-probes in the versions files.")
+	detectAllInClusterCmd.PersistentFlags().StringVar(&compareTo, "compare-to", "", "A previous -o json report to compare to. Findings are shown as NEW, FIXED or UNCHANGED and only NEW findings affect the exit code.")
 
 	rootCmd.AddCommand(listVersionsCmd)
 	rootCmd.AddCommand(detectCmd)
+	detectCmd.PersistentFlags().StringVar(&compareTo, "compare-to", "", "A previous -o json report to compare to. Findings are shown as NEW, FIXED or UNCHANGED and only NEW findings affect the exit code.")
 
 	klog.InitFlags(nil)
 	pflag.CommandLine.AddGoFlag(flag.CommandLine.Lookup("v"))
@@ -170,6 +220,14 @@ var rootCmd = &cobra.Command{
 			os.Exit(exitCode)
 		}
 
+		if apiInstance != nil {
+			if summary := apiInstance.SuppressionSummary(); summary != "" {
+				os.Stderr.WriteString("\n" + summary + "\n")
+			}
+			if summary := apiInstance.ChangeSummary(); summary != "" {
+				os.Stderr.WriteString("\n" + summary + "\n")
+			}
+		}
 		os.Stderr.WriteString("\n\nWant more? Automate lamb for free with lamb Insights!\n 🚀example comment:will fill in later time 🚀 \n")
 		klog.V(5).Infof("exiting with code %d", exitCode)
 		os.Exit(exitCode)
@@ -181,7 +239,13 @@ var rootCmd = &cobra.Command{
 		}
 
 		//verify output option
-		if !api.StringInSlice(outputFormat, outputOptions) {
+		format, text, found, err := api.SplitTemplateOutput(outputFormat)
+		if err != nil {
+			return err
+		}
+		if found {
+			outputFormat, outputTemplate = format, text
+		} else if !api.StringInSlice(outputFormat, outputOptions) {
 			return fmt.Errorf("--output must be one of %v", outputOptions)
 		}
 
@@ -206,7 +270,7 @@ var rootCmd = &cobra.Command{
 			}
 		}
 
-		defaultVersions, defaultTargetVersions, err := api.GetDefaultVersionList(versionFileData)
+		defaultVersionFile, err := api.UnMarshalVersionFile(versionFileData)
 		if err != nil {
 			return err
 		}
@@ -215,32 +279,57 @@ var rootCmd = &cobra.Command{
 			return err
 		}
 
-		var deprecatedVersionList []api.Version
-		if additionalVersionsFile != "" {
-			klog.V(2).Infof("looking for versions file: %s", additionalVersionsFile)
-			data, err := os.ReadFile(additionalVersionsFile)
+		versionFiles := additionalVersionsFiles
+		if versionsURL != "" {
+			if !api.IsURL(versionsURL) {
+				return fmt.Errorf("--versions-url must be an http(s) URL, got %s", versionsURL)
+			}
+			versionFiles = append([]string{versionsURL}, versionFiles...)
+		}
+		if len(versionFiles) == 0 {
+			klog.V(2).Info("no additional versions needed")
+		}
+		fetcher := &api.VersionFetcher{CacheDir: versionsCacheDir}
+		if versionsPublicKeyFile != "" {
+			data, err := os.ReadFile(versionsPublicKeyFile)
 			if err != nil {
 				return err
 			}
-			additionalVersions, additionalTargetVersions, err := api.UnMarshalVersions(data)
+			fetcher.PublicKey, err = api.ParsePublicKey(data)
 			if err != nil {
 				return err
 			}
-			deprecatedVersionList, err = api.CombineAdditionalVersions(additionalVersions, defaultVersions)
+		}
+		// target versions of the built-in versions file win over those of additional files,
+		// and later additional files win over earlier ones
+		versionFile, err := api.MergeVersionFiles(versionFiles, *defaultVersionFile, strictVersions, fetcher)
+		if err != nil {
+			return err
+		}
+		deprecatedVersionList, defaultTargetVersions := versionFile.DeprecatedVersions, versionFile.TargetVersions
+
+		// platform target versions take precedence over the defaults, but not over --target-versions
+		platformTargetVersions, err := versionFile.Platforms.TargetVersions(targetPlatforms)
+		if err != nil {
+			return fmt.Errorf("invalid --target-platform: %w", err)
+		}
+		for component, version := range platformTargetVersions {
+			klog.V(2).Infof("using %s %s from --target-platform", component, version)
+			defaultTargetVersions[component] = version
+		}
+
+		// versions read from the cluster take precedence over platform target versions
+		if targetFromCluster {
+			inferred, err := clusterTargetVersions(versionFile.ComponentProbes)
 			if err != nil {
 				return err
 			}
-			for c, v := range additionalTargetVersions {
-				klog.V(2).Infof("received target version from config: %s %s", c, v)
-				// Only add them to default target versions if they do not supersed any previously included
-				// This prevents overwriting the internal defaults
-				if _, found := defaultTargetVersions[c]; !found {
-					defaultTargetVersions[c] = v
-				}
+			for _, v := range inferred {
+				fmt.Fprintf(os.Stderr, "using target version %s %s from %s\n", v.Component, v.Version, v.Source)
+				defaultTargetVersions[v.Component] = v.Version
 			}
-		} else {
-			klog.V(2).Info("no additional versions needed")
-			deprecatedVersionList = defaultVersions
+		} else if probeComponents {
+			return fmt.Errorf("--probe-components requires --target-from-cluster")
 		}
 
 		var depricatedTypesList []api.Type
@@ -313,8 +402,8 @@ var rootCmd = &cobra.Command{
 
 		// verify that we have valid target versions for all components
 		for component, version := range targetVersions {
-			if !semver.IsValid(version) {
-				return fmt.Errorf("you must use valid semver for all target versions with a leading 'v' - got %s %s", component, version)
+			if !api.IsValidVersion(version) {
+				return fmt.Errorf("you must use a valid version such as v1.27.3 or 1.27 for all target versions - got %s %s", component, version)
 			}
 		}
 		for _, c := range componentList {
@@ -323,19 +412,77 @@ var rootCmd = &cobra.Command{
 			}
 		}
 
+		// rules do not depend on target versions, so their components are added after the check
+		var rules *api.Rules
+		if rulesFile != "" {
+			rules, err = api.LoadRules(rulesFile)
+			if err != nil {
+				return err
+			}
+			for _, c := range rules.Components() {
+				if !api.StringInSlice(c, componentList) && (componentsFromUser == nil || api.StringInSlice(c, componentsFromUser)) {
+					componentList = append(componentList, c)
+				}
+			}
+		}
+
 		// this apiInstance will be used by all detection methods
 		apiInstance = &api.Instance{
 			TargetVersions:                targetVersions,
 			TargetTypes:                   targetTypes,
 			OutputFormat:                  outputFormat,
+			OutputTemplate:                outputTemplate,
 			CustomColumns:                 customColumns,
 			IgnoreDeprecations:            ignoreDeprecations,
 			IgnoreRemovals:                ignoreRemovals,
 			IgnoreUnavailableReplacements: ignoreUnavailableReplacements,
 			OnlyShowRemoved:               onlyShowRemoved,
 			NoHeaders:                     noHeaders,
+			ShowSuppressed:                showSuppressed,
 			DeprecatedVersions:            deprecatedVersionList,
 			Components:                    componentList,
+			Rules:                         rules,
+			TrackObjects:                  outputFormat == "junit",
+		}
+		apiInstance.BuildIndex()
+
+		if baselineFile != "" {
+			baseline, err := api.LoadBaseline(baselineFile)
+			if err != nil {
+				return err
+			}
+			if expired := baseline.Expired(time.Now()); len(expired) > 0 {
+				for _, entry := range expired {
+					fmt.Fprintf(os.Stderr, "baseline entry for %s expired on %s (owner %s): %s\n", entry, entry.Expires, entry.Owner, entry.Reason)
+				}
+				return fmt.Errorf("%d baseline entries in %s have expired", len(expired), baselineFile)
+			}
+			apiInstance.Baseline = baseline
+		}
+
+		if policyFile != "" && len(failOn) > 0 {
+			return fmt.Errorf("--policy and --fail-on cannot be used together")
+		}
+		if policyFile != "" {
+			policy, err := api.LoadPolicy(policyFile)
+			if err != nil {
+				return err
+			}
+			apiInstance.Policy = policy
+		} else if len(failOn) > 0 {
+			policy, err := api.FailOnPolicy(failOn)
+			if err != nil {
+				return err
+			}
+			apiInstance.Policy = policy
+		}
+
+		if compareTo != "" {
+			previous, err := readReport(compareTo)
+			if err != nil {
+				return err
+			}
+			apiInstance.CompareTo = previous
 		}
 
 		return nil
@@ -348,6 +495,10 @@ var detectFilesCmd = &cobra.Command{
 	Long:  `Detect Kubernetes apiVersions in a directory.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		dir := finder.NewFinder(directory, apiInstance)
+		dir.Kustomize = kustomize
+		dir.HelmCharts = helmCharts
+		dir.HelmValues = helmValues
+		dir.HelmSet = helmSet
 		err := dir.FindVersions()
 		if err != nil {
 			fmt.Println("Error running finder:", err)
@@ -552,6 +703,30 @@ func detectHelm() error {
 	return nil
 }
 
+// clusterTargetVersions returns the target versions read from the cluster of the kube context,
+// the k8s version from the API server and, with --probe-components, the versions of components
+// found by probes
+func clusterTargetVersions(probes api.ComponentProbes) ([]kube.InferredVersion, error) {
+	k, err := kube.GetConfigInstance(kubeContext)
+	if err != nil {
+		return nil, fmt.Errorf("error getting kube client for --target-from-cluster: %v", err)
+	}
+	server, err := kube.ServerTargetVersion(k.Client)
+	if err != nil {
+		return nil, err
+	}
+	inferred := []kube.InferredVersion{*server}
+	if probeComponents {
+		for _, v := range kube.ProbeComponentVersions(k.Client, probes) {
+			if v.Component == server.Component {
+				continue
+			}
+			inferred = append(inferred, v)
+		}
+	}
+	return inferred, nil
+}
+
 func detectAPIResources() error {
 	disCl, err := discoveryapi.NewDiscoveryClient(namespace, kubeContext, apiInstance)
 	if err != nil {
@@ -0,0 +1,103 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"github.com/danielpickens/lamb/v5/pkg/api"
+	"github.com/danielpickens/lamb/v5/pkg/finder"
+)
+
+var (
+	upgradeFrom map[string]string
+	upgradeTo   map[string]string
+)
+
+func init() {
+	rootCmd.AddCommand(upgradePlanCmd)
+	upgradePlanCmd.PersistentFlags().StringToStringVar(&upgradeFrom, "from", nil, "A map of the versions being upgraded from, such as k8s=v1.24.0.")
+	upgradePlanCmd.PersistentFlags().StringToStringVar(&upgradeTo, "to", nil, "A map of the versions being upgraded to, such as k8s=v1.29.0.")
+	upgradePlanCmd.PersistentFlags().StringVarP(&directory, "directory", "d", "", "The directory to scan when no file is passed. If blank, defaults to current working dir.")
+	upgradePlanCmd.PersistentFlags().BoolVar(&helmCharts, "helm-charts", false, "Render Helm charts found in the directory and scan the output instead of the files inside them.")
+	upgradePlanCmd.PersistentFlags().StringSliceVar(&helmValues, "helm-values", []string{}, "Values files used when rendering Helm charts found in the directory. Can be passed multiple times.")
+	upgradePlanCmd.PersistentFlags().StringArrayVar(&helmSet, "helm-set", []string{}, "Values used when rendering Helm charts found in the directory, in the form key=value. Can be passed multiple times.")
+	upgradePlanCmd.PersistentFlags().BoolVar(&kustomize, "kustomize", false, "Render kustomizations found in the directory and scan the output instead of the files inside them.")
+	_ = upgradePlanCmd.MarkPersistentFlagRequired("from")
+	_ = upgradePlanCmd.MarkPersistentFlagRequired("to")
+}
+
+var upgradePlanCmd = &cobra.Command{
+	Use:   "upgrade-plan [file to check or -]",
+	Short: "Shows what breaks at each minor version of an upgrade.",
+	Long:  `Scans a file, stdin or directory once and shows, for every minor version between --from and --to, which apiVersions become deprecated or are removed and whether their replacements are available.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 || api.IsFileOrStdin(args[0]) {
+			return nil
+		}
+		return fmt.Errorf("invalid file specified: %s", args[0])
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		// charts are rendered for the version being upgraded to
+		for component, version := range upgradeTo {
+			apiInstance.TargetVersions[component] = version
+		}
+
+		var err error
+		switch {
+		case len(args) > 0 && args[0] == "-":
+			var data []byte
+			data, err = io.ReadAll(os.Stdin)
+			if err != nil {
+				fmt.Println("Error reading stdin:", err)
+				os.Exit(1)
+			}
+			apiInstance.Outputs, err = apiInstance.IsVersioned(data)
+		case len(args) > 0:
+			dir := finder.Dir{
+				Instance: apiInstance,
+			}
+			apiInstance.Outputs, err = dir.CheckForAPIVersion(args[0])
+		default:
+			dir := finder.NewFinder(directory, apiInstance)
+			dir.Kustomize = kustomize
+			dir.HelmCharts = helmCharts
+			dir.HelmValues = helmValues
+			dir.HelmSet = helmSet
+			err = dir.FindVersions()
+		}
+		if err != nil {
+			fmt.Println("Error checking for versions:", err)
+			os.Exit(1)
+		}
+
+		plan, err := apiInstance.PlanUpgrade(upgradeFrom, upgradeTo)
+		if err != nil {
+			fmt.Println("Error planning upgrade:", err)
+			os.Exit(1)
+		}
+		klog.V(5).Infof("planned %d upgrade steps", len(plan.Steps))
+		err = apiInstance.DisplayUpgradePlan(plan)
+		if err != nil {
+			fmt.Println("Error parsing output:", err)
+			os.Exit(1)
+		}
+	},
+}
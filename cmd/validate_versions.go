@@ -0,0 +1,59 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/danielpickens/lamb/v5/pkg/api"
+)
+
+func init() {
+	rootCmd.AddCommand(validateVersionsCmd)
+}
+
+var validateVersionsCmd = &cobra.Command{
+	Use:   "validate-versions [file ...]",
+	Short: "Checks custom versions files for mistakes.",
+	Long:  `Checks versions files for unknown keys, invalid semver, release versions out of order, replacements removed along with the versions they replace, components without a target version and duplicate kinds and versions. Every problem is printed with its file and line. Components may take their target version from the built-in versions file.`,
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		_, defaultTargetVersions, err := api.GetDefaultVersionList(versionFileData)
+		if err != nil {
+			fmt.Println("Error reading the default versions:", err)
+			os.Exit(1)
+		}
+		for _, file := range args {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				fmt.Println("Error reading versions file:", err)
+				exitCode = 1
+				continue
+			}
+			errs := api.ValidateVersionFile(file, data, defaultTargetVersions)
+			for _, err := range errs {
+				fmt.Println(err.Error())
+			}
+			if len(errs) > 0 {
+				exitCode = 1
+				continue
+			}
+			fmt.Printf("%s is valid\n", file)
+		}
+	},
+}
@@ -0,0 +1,201 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BaselineDateFormat is the format of the expires date of a baseline entry
+const BaselineDateFormat = "2006-01-02"
+
+// Baseline is a list of accepted findings. Outputs matching an entry are still shown,
+// but marked as suppressed and left out of the return code.
+type Baseline struct {
+	Suppressions []BaselineEntry `json:"suppressions" yaml:"suppressions"`
+}
+
+// BaselineEntry is an accepted finding. Empty file, namespace, name, kind, apiVersion and
+// field match any value.
+type BaselineEntry struct {
+	File       string `json:"file,omitempty" yaml:"file,omitempty"`
+	Namespace  string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Name       string `json:"name,omitempty" yaml:"name,omitempty"`
+	Kind       string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	APIVersion string `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	Field      string `json:"field,omitempty" yaml:"field,omitempty"`
+	// Owner is who accepted the finding
+	Owner string `json:"owner" yaml:"owner"`
+	// Reason is why the finding was accepted
+	Reason string `json:"reason" yaml:"reason"`
+	// Expires is the date, such as 2024-12-31, after which the entry fails the run
+	Expires string `json:"expires" yaml:"expires"`
+}
+
+// String describes the finding an entry matches
+func (entry BaselineEntry) String() string {
+	description := fmt.Sprintf("%s %s", entry.Kind, entry.APIVersion)
+	if entry.Field != "" {
+		description = description + " field " + entry.Field
+	}
+	if entry.Namespace != "" {
+		description = description + " " + entry.Namespace + "/" + entry.Name
+	} else if entry.Name != "" {
+		description = description + " " + entry.Name
+	}
+	if entry.File != "" {
+		description = description + " in " + entry.File
+	}
+	return description
+}
+
+// expiresAt returns the time at the end of the expires date, in UTC
+func (entry BaselineEntry) expiresAt() (time.Time, error) {
+	expires, err := time.Parse(BaselineDateFormat, entry.Expires)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return expires.AddDate(0, 0, 1), nil
+}
+
+// matches returns whether output is the finding of the entry
+func (entry BaselineEntry) matches(output *Output) bool {
+	if output.APIVersion == nil {
+		return false
+	}
+	for _, check := range [][2]string{
+		{entry.File, output.FilePath},
+		{entry.Namespace, output.Namespace},
+		{entry.Name, output.Name},
+		{entry.Kind, output.APIVersion.Kind},
+		{entry.APIVersion, output.APIVersion.Name},
+		{entry.Field, output.Field},
+	} {
+		if check[0] != "" && check[0] != check[1] {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadBaseline reads a baseline file. Every entry must have an owner, a reason and an
+// expires date.
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	baseline := &Baseline{}
+	if err := yaml.Unmarshal(data, baseline); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	for i, entry := range baseline.Suppressions {
+		if entry.Owner == "" || entry.Reason == "" || entry.Expires == "" {
+			return nil, fmt.Errorf("%s: suppression %d (%s) must have an owner, a reason and an expires date", path, i+1, entry)
+		}
+		if _, err := entry.expiresAt(); err != nil {
+			return nil, fmt.Errorf("%s: suppression %d (%s) has an invalid expires date %q, must be like %s", path, i+1, entry, entry.Expires, BaselineDateFormat)
+		}
+	}
+	return baseline, nil
+}
+
+// Expired returns the entries that have expired at now
+func (baseline *Baseline) Expired(now time.Time) []BaselineEntry {
+	var expired []BaselineEntry
+	for _, entry := range baseline.Suppressions {
+		expiresAt, err := entry.expiresAt()
+		if err != nil || !now.Before(expiresAt) {
+			expired = append(expired, entry)
+		}
+	}
+	return expired
+}
+
+// match returns the first entry matching output, or nil if there is none
+func (baseline *Baseline) match(output *Output) *BaselineEntry {
+	if baseline == nil {
+		return nil
+	}
+	for i := range baseline.Suppressions {
+		if baseline.Suppressions[i].matches(output) {
+			return &baseline.Suppressions[i]
+		}
+	}
+	return nil
+}
+
+// NewBaseline returns a baseline that suppresses every one of outputs, sorted by file,
+// namespace, name, kind, apiVersion and field
+func NewBaseline(outputs []*Output, owner string, reason string, expires string) *Baseline {
+	baseline := &Baseline{Suppressions: []BaselineEntry{}}
+	seen := map[BaselineEntry]bool{}
+	for _, output := range outputs {
+		if output.APIVersion == nil {
+			continue
+		}
+		entry := BaselineEntry{
+			File:       output.FilePath,
+			Namespace:  output.Namespace,
+			Name:       output.Name,
+			Kind:       output.APIVersion.Kind,
+			APIVersion: output.APIVersion.Name,
+			Field:      output.Field,
+			Owner:      owner,
+			Reason:     reason,
+			Expires:    expires,
+		}
+		if seen[entry] {
+			continue
+		}
+		seen[entry] = true
+		baseline.Suppressions = append(baseline.Suppressions, entry)
+	}
+	sort.Slice(baseline.Suppressions, func(i, j int) bool {
+		a, b := baseline.Suppressions[i], baseline.Suppressions[j]
+		for _, pair := range [][2]string{
+			{a.File, b.File},
+			{a.Namespace, b.Namespace},
+			{a.Name, b.Name},
+			{a.Kind, b.Kind},
+			{a.APIVersion, b.APIVersion},
+			{a.Field, b.Field},
+		} {
+			if pair[0] != pair[1] {
+				return pair[0] < pair[1]
+			}
+		}
+		return false
+	})
+	return baseline
+}
+
+// ApplyBaseline marks the outputs matching the baseline of the instance as suppressed
+func (instance *Instance) ApplyBaseline() {
+	for _, output := range instance.Outputs {
+		if output.Suppressed {
+			continue
+		}
+		if entry := instance.Baseline.match(output); entry != nil {
+			output.Suppressed = true
+			output.SuppressedBy = fmt.Sprintf("baseline: %s (owner %s, expires %s)", entry.Reason, entry.Owner, entry.Expires)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeBaseline(t *testing.T, data string) string {
+	path := filepath.Join(t.TempDir(), ".lamb-baseline.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(data), 0644))
+	return path
+}
+
+func TestLoadBaseline(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    *Baseline
+		wantErr string
+	}{
+		{
+			name: "valid",
+			data: `suppressions:
+- file: manifests/ingress.yaml
+  name: web
+  kind: Ingress
+  apiVersion: extensions/v1beta1
+  owner: team-web
+  reason: migrating with the next release
+  expires: 2024-12-31
+`,
+			want: &Baseline{Suppressions: []BaselineEntry{{
+				File:       "manifests/ingress.yaml",
+				Name:       "web",
+				Kind:       "Ingress",
+				APIVersion: "extensions/v1beta1",
+				Owner:      "team-web",
+				Reason:     "migrating with the next release",
+				Expires:    "2024-12-31",
+			}}},
+		},
+		{
+			name: "missing owner",
+			data: `suppressions:
+- kind: Ingress
+  apiVersion: extensions/v1beta1
+  reason: migrating with the next release
+  expires: 2024-12-31
+`,
+			wantErr: "suppression 1 (Ingress extensions/v1beta1) must have an owner, a reason and an expires date",
+		},
+		{
+			name: "invalid expires",
+			data: `suppressions:
+- kind: Ingress
+  apiVersion: extensions/v1beta1
+  owner: team-web
+  reason: migrating with the next release
+  expires: 31/12/2024
+`,
+			wantErr: `suppression 1 (Ingress extensions/v1beta1) has an invalid expires date "31/12/2024", must be like 2006-01-02`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeBaseline(t, tt.data)
+			got, err := LoadBaseline(path)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, path+": "+tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBaseline_Expired(t *testing.T) {
+	baseline := &Baseline{Suppressions: []BaselineEntry{
+		{Kind: "Ingress", Expires: "2024-06-30"},
+		{Kind: "Deployment", Expires: "2024-07-01"},
+	}}
+	assert.Empty(t, baseline.Expired(time.Date(2024, 6, 30, 23, 59, 0, 0, time.UTC)))
+	assert.Equal(t, []BaselineEntry{{Kind: "Ingress", Expires: "2024-06-30"}}, baseline.Expired(time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestInstance_ApplyBaseline(t *testing.T) {
+	ingress := &Output{Name: "web", FilePath: "manifests/ingress.yaml", APIVersion: &Version{Name: "extensions/v1beta1", Kind: "Ingress"}}
+	otherIngress := &Output{Name: "api", FilePath: "manifests/ingress.yaml", APIVersion: &Version{Name: "extensions/v1beta1", Kind: "Ingress"}}
+	deployment := &Output{Name: "web", Namespace: "prod", APIVersion: &Version{Name: "apps/v1beta1", Kind: "Deployment"}}
+	instance := &Instance{
+		Outputs: []*Output{ingress, otherIngress, deployment},
+		Baseline: &Baseline{Suppressions: []BaselineEntry{
+			{File: "manifests/ingress.yaml", Name: "web", Kind: "Ingress", APIVersion: "extensions/v1beta1", Owner: "team-web", Reason: "next release", Expires: "2024-12-31"},
+			{Namespace: "prod", Kind: "Deployment", Owner: "team-ops", Reason: "vendor chart", Expires: "2024-12-31"},
+		}},
+	}
+	instance.ApplyBaseline()
+
+	assert.True(t, ingress.Suppressed)
+	assert.Equal(t, "baseline: next release (owner team-web, expires 2024-12-31)", ingress.SuppressedBy)
+	assert.False(t, otherIngress.Suppressed)
+	assert.True(t, deployment.Suppressed)
+	assert.Equal(t, "SUPPRESSED", status{}.value(ingress))
+	assert.Equal(t, "", status{}.value(otherIngress))
+}
+
+func TestNewBaseline(t *testing.T) {
+	outputs := []*Output{
+		{Name: "web", FilePath: "b.yaml", APIVersion: &Version{Name: "extensions/v1beta1", Kind: "Ingress"}},
+		{Name: "web", FilePath: "a.yaml", APIVersion: &Version{Name: "extensions/v1beta1", Kind: "Ingress"}, Field: "spec.backend"},
+		{Name: "web", FilePath: "a.yaml", APIVersion: &Version{Name: "extensions/v1beta1", Kind: "Ingress"}, Field: "spec.backend"},
+	}
+	assert.Equal(t, &Baseline{Suppressions: []BaselineEntry{
+		{File: "a.yaml", Name: "web", Kind: "Ingress", APIVersion: "extensions/v1beta1", Field: "spec.backend", Owner: "team-web", Reason: "accepted", Expires: "2024-12-31"},
+		{File: "b.yaml", Name: "web", Kind: "Ingress", APIVersion: "extensions/v1beta1", Owner: "team-web", Reason: "accepted", Expires: "2024-12-31"},
+	}}, NewBaseline(outputs, "team-web", "accepted", "2024-12-31"))
+}
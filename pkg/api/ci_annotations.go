@@ -0,0 +1,174 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// repositoryRootVariables are the environment variables of CI systems with the directory
+// of the checked out repository
+var repositoryRootVariables = []string{"GITHUB_WORKSPACE", "CI_PROJECT_DIR"}
+
+// githubLevels are the workflow commands of the severities of findings
+var githubLevels = map[Severity]string{
+	SeverityError: "error",
+	SeverityWarn:  "warning",
+	SeverityInfo:  "notice",
+}
+
+// gitlabSeverities are the Code Quality severities of the severities of findings
+var gitlabSeverities = map[Severity]string{
+	SeverityError: "major",
+	SeverityWarn:  "minor",
+	SeverityInfo:  "info",
+}
+
+// gitlabIssue is a finding in the GitLab Code Quality report, see
+// https://docs.gitlab.com/ee/ci/testing/code_quality.html#implement-a-custom-tool
+type gitlabIssue struct {
+	Description string         `json:"description"`
+	CheckName   string         `json:"check_name"`
+	Fingerprint string         `json:"fingerprint"`
+	Severity    string         `json:"severity"`
+	Location    gitlabLocation `json:"location"`
+}
+
+type gitlabLocation struct {
+	Path  string      `json:"path"`
+	Lines gitlabLines `json:"lines"`
+}
+
+type gitlabLines struct {
+	Begin int `json:"begin"`
+}
+
+// repositoryRoot returns the root of the repository of an absolute path, from the
+// environment of the CI system or else the nearest directory with a .git
+func repositoryRoot(path string) string {
+	for _, variable := range repositoryRootVariables {
+		if root := os.Getenv(variable); root != "" {
+			return root
+		}
+	}
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		if dir == filepath.Dir(dir) {
+			return ""
+		}
+	}
+}
+
+// repositoryPath returns the path of a file relative to the root of its repository if it is
+// absolute, since annotations are matched to the files of the repository
+func repositoryPath(path string) string {
+	if !filepath.IsAbs(path) {
+		return filepath.ToSlash(filepath.Clean(path))
+	}
+	root := repositoryRoot(path)
+	if root == "" {
+		return filepath.ToSlash(path)
+	}
+	relative, err := filepath.Rel(root, path)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(relative)
+}
+
+// annotatedOutputs calls annotate with every finding of the outputs and its severity.
+// Suppressed and fixed findings are left out, as they have nothing to annotate.
+func (instance *Instance) annotatedOutputs(annotate func(output *Output, severity Severity)) {
+	policy := instance.policy()
+	for _, output := range instance.Outputs {
+		if output.Change == ChangeFixed {
+			continue
+		}
+		severity, _, ok := policy.outcome(output, instance.TargetVersions)
+		if !ok {
+			continue
+		}
+		annotate(output, severity)
+	}
+}
+
+// githubEscapeData escapes the message of a workflow command
+func githubEscapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// githubEscapeProperty escapes a property of a workflow command
+func githubEscapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// github returns a GitHub Actions workflow command for every finding, which annotates its
+// file and line in pull requests. Findings without a file annotate the workflow run.
+func (instance *Instance) github() []string {
+	var commands []string
+	instance.annotatedOutputs(func(output *Output, severity Severity) {
+		var properties []string
+		if output.FilePath != "" {
+			properties = append(properties, "file="+githubEscapeProperty(repositoryPath(output.FilePath)))
+			if output.Line > 0 {
+				properties = append(properties, fmt.Sprintf("line=%d", output.Line))
+			}
+			if output.Column > 0 {
+				properties = append(properties, fmt.Sprintf("col=%d", output.Column))
+			}
+		}
+		properties = append(properties, "title="+githubEscapeProperty(newSarifRule(output).ShortDescription.Text))
+		commands = append(commands, fmt.Sprintf("::%s %s::%s", githubLevels[severity], strings.Join(properties, ","), githubEscapeData(findingMessage(output))))
+	})
+	return commands
+}
+
+// gitlabCodeQuality returns a GitLab Code Quality issue for every finding. Findings without a
+// file have the namespace and name as their path. The fingerprint does not depend on the line,
+// so that moving an object does not make it a new issue.
+func (instance *Instance) gitlabCodeQuality() []gitlabIssue {
+	issues := []gitlabIssue{}
+	instance.annotatedOutputs(func(output *Output, severity Severity) {
+		path := output.Name
+		if output.Namespace != "" {
+			path = output.Namespace + "/" + output.Name
+		}
+		if output.FilePath != "" {
+			path = repositoryPath(output.FilePath)
+		}
+		identity := *output
+		identity.FilePath = path
+		fingerprint := sha256.Sum256([]byte(findingIdentity(&identity)))
+		line := output.Line
+		if line < 1 {
+			line = 1
+		}
+		issues = append(issues, gitlabIssue{
+			Description: findingMessage(output),
+			CheckName:   sarifRuleID(output),
+			Fingerprint: hex.EncodeToString(fingerprint[:]),
+			Severity:    gitlabSeverities[severity],
+			Location:    gitlabLocation{Path: path, Lines: gitlabLines{Begin: line}},
+		})
+	})
+	return issues
+}
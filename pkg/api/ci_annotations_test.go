@@ -0,0 +1,110 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryPath(t *testing.T) {
+	repo := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(repo, ".git"), 0755))
+	outside := t.TempDir()
+
+	tests := []struct {
+		name      string
+		workspace string
+		path      string
+		want      string
+	}{
+		{name: "relative", path: "./manifests/deploy.yaml", want: "manifests/deploy.yaml"},
+		{name: "absolute in a git repository", path: filepath.Join(repo, "manifests", "deploy.yaml"), want: "manifests/deploy.yaml"},
+		{name: "absolute in the workspace", workspace: outside, path: filepath.Join(outside, "deploy.yaml"), want: "deploy.yaml"},
+		{name: "absolute outside the workspace", workspace: repo, path: filepath.Join(outside, "deploy.yaml"), want: filepath.ToSlash(filepath.Join(outside, "deploy.yaml"))},
+		{name: "absolute outside a repository", path: filepath.Join(outside, "deploy.yaml"), want: filepath.ToSlash(filepath.Join(outside, "deploy.yaml"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITHUB_WORKSPACE", tt.workspace)
+			t.Setenv("CI_PROJECT_DIR", "")
+			assert.Equal(t, tt.want, repositoryPath(tt.path))
+		})
+	}
+}
+
+func annotationsInstance() *Instance {
+	deployment := &Version{Name: "extensions/v1beta1", Kind: "Deployment", DeprecatedIn: "v1.9.0", RemovedIn: "v1.16.0", ReplacementAPI: "apps/v1", ReplacementAvailableIn: "v1.9.0", Component: "k8s"}
+	psp := &Version{Name: "policy/v1beta1", Kind: "PodSecurityPolicy", DeprecatedIn: "v1.21.0", RemovedIn: "v1.25.0", ReplacementAvailableIn: "v1.21.0", Component: "k8s"}
+	return &Instance{
+		Outputs: []*Output{
+			{Name: "web", FilePath: "deploy.yaml", Line: 3, Column: 1, APIVersion: deployment, Deprecated: true, Removed: true},
+			{Name: "restricted", FilePath: "psp,v1.yaml", Line: 1, APIVersion: psp, Deprecated: true},
+			{Name: "release/old", Namespace: "apps", APIVersion: deployment, Deprecated: true, Removed: true},
+			{Name: "ignored", FilePath: "deploy.yaml", Line: 20, APIVersion: deployment, Deprecated: true, Removed: true, Suppressed: true},
+			{Name: "gone", FilePath: "deploy.yaml", Line: 40, APIVersion: deployment, Deprecated: true, Removed: true, Change: ChangeFixed},
+			{Name: "web", FilePath: "deploy.yaml", Line: 3, APIVersion: &Version{Name: "extensions/v1beta1", Kind: "Deployment", Component: "platform"}, Rule: "replicas", Message: "set 100% of\nreplicas", Severity: SeverityInfo},
+		},
+		TargetVersions: map[string]string{"k8s": "v1.22.0"},
+	}
+}
+
+func TestInstance_github(t *testing.T) {
+	t.Setenv("GITHUB_WORKSPACE", "")
+	t.Setenv("CI_PROJECT_DIR", "")
+	assert.Equal(t, []string{
+		"::error file=deploy.yaml,line=3,col=1,title=Deployment extensions/v1beta1 is deprecated::Deployment web uses extensions/v1beta1, which is removed. Replace it with apps/v1.",
+		"::warning file=psp%2Cv1.yaml,line=1,title=PodSecurityPolicy policy/v1beta1 is deprecated::PodSecurityPolicy restricted uses policy/v1beta1, which is deprecated.",
+		"::error title=Deployment extensions/v1beta1 is deprecated::Deployment release/old uses extensions/v1beta1, which is removed. Replace it with apps/v1.",
+		"::notice file=deploy.yaml,line=3,title=replicas::Deployment web: set 100%25 of%0Areplicas",
+	}, annotationsInstance().github())
+}
+
+func TestInstance_gitlabCodeQuality(t *testing.T) {
+	t.Setenv("GITHUB_WORKSPACE", "")
+	t.Setenv("CI_PROJECT_DIR", "")
+	issues := annotationsInstance().gitlabCodeQuality()
+	assert.Len(t, issues, 4)
+
+	assert.Equal(t, "Deployment web uses extensions/v1beta1, which is removed. Replace it with apps/v1.", issues[0].Description)
+	assert.Equal(t, "k8s/Deployment/extensions/v1beta1", issues[0].CheckName)
+	assert.Equal(t, "major", issues[0].Severity)
+	assert.Equal(t, gitlabLocation{Path: "deploy.yaml", Lines: gitlabLines{Begin: 3}}, issues[0].Location)
+	assert.Equal(t, "minor", issues[1].Severity)
+	assert.Equal(t, gitlabLocation{Path: "apps/release/old", Lines: gitlabLines{Begin: 1}}, issues[2].Location)
+	assert.Equal(t, "platform/replicas", issues[3].CheckName)
+	assert.Equal(t, "info", issues[3].Severity)
+
+	fingerprints := map[string]bool{}
+	for _, issue := range issues {
+		assert.Len(t, issue.Fingerprint, 64)
+		fingerprints[issue.Fingerprint] = true
+	}
+	assert.Len(t, fingerprints, 4)
+
+	// the fingerprint does not change when the object moves within its file or the file is
+	// scanned by its absolute path
+	repo := t.TempDir()
+	t.Setenv("CI_PROJECT_DIR", repo)
+	moved := annotationsInstance()
+	moved.Outputs[0].Line = 30
+	moved.Outputs[0].FilePath = filepath.Join(repo, "deploy.yaml")
+	assert.Equal(t, issues[0].Fingerprint, moved.gitlabCodeQuality()[0].Fingerprint)
+
+	assert.Equal(t, []gitlabIssue{}, (&Instance{}).gitlabCodeQuality())
+}
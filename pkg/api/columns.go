@@ -28,9 +28,13 @@ type columnList map[int]column
 var PossibleColumnNames = []string{
 	"NAME",
 	"FILEPATH",
+	"DOCUMENT",
+	"LINE",
+	"COLUMN",
 	"NAMESPACE",
 	"KIND",
 	"VERSION",
+	"FIELD",
 	"TYPE",
 	"REPLACEMENT",
 	"DEPRECATED",
@@ -40,6 +44,10 @@ var PossibleColumnNames = []string{
 	"COMPONENT",
 	"REPL AVAIL",
 	"REPL AVAIL IN",
+	"STATUS",
+	"RULE",
+	"MESSAGE",
+	"CHANGE",
 }
 
 var possibleColumns = []column{
@@ -47,6 +55,7 @@ var possibleColumns = []column{
 	new(namespace),
 	new(kind),
 	new(version),
+	new(field),
 	new(typeColumn),
 	new(replacement),
 	new(deprecated),
@@ -54,9 +63,16 @@ var possibleColumns = []column{
 	new(removed),
 	new(removedIn),
 	new(component),
-	new(filepath),
+	new(filePathColumn),
+	new(documentNumber),
+	new(line),
+	new(columnNumber),
 	new(replacementAvailable),
 	new(replacementAvailableIn),
+	new(status),
+	new(rule),
+	new(message),
+	new(change),
 }
 
 // name is the output name
@@ -65,17 +81,50 @@ type name struct{}
 func (n name) header() string              { return "NAME" }
 func (n name) value(output *Output) string { return output.Name }
 
-// filepath is the full path of the file
-type filepath struct{}
+// filePathColumn is the full path of the file
+type filePathColumn struct{}
 
-func (f filepath) header() string { return "FILEPATH" }
-func (f filepath) value(output *Output) string {
+func (f filePathColumn) header() string { return "FILEPATH" }
+func (f filePathColumn) value(output *Output) string {
 	if output.FilePath == "" {
 		return "<UNKNOWN>"
 	}
 	return output.FilePath
 }
 
+// documentNumber is the number of the document in the file
+type documentNumber struct{}
+
+func (d documentNumber) header() string { return "DOCUMENT" }
+func (d documentNumber) value(output *Output) string {
+	if output.Document == 0 {
+		return "<UNKNOWN>"
+	}
+	return fmt.Sprintf("%d", output.Document)
+}
+
+// line is the line of the apiVersion key in the file
+type line struct{}
+
+func (l line) header() string { return "LINE" }
+func (l line) value(output *Output) string {
+	if output.Line == 0 {
+		return "<UNKNOWN>"
+	}
+	return fmt.Sprintf("%d", output.Line)
+}
+
+// columnNumber is the column of the apiVersion key in the file
+type columnNumber struct{}
+
+func (c columnNumber) header() string { return "COLUMN" }
+func (c columnNumber) value(output *Output) string {
+	if output.Column == 0 {
+		return "<UNKNOWN>"
+	}
+	return fmt.Sprintf("%d", output.Column)
+}
+
 // namespace is the output namespace if available
 type namespace struct{}
 
@@ -99,6 +148,17 @@ type version struct{}
 func (v version) header() string              { return "VERSION" }
 func (v version) value(output *Output) string { return output.APIVersion.Name }
 
+// field is the path of the deprecated field, if the output is for a field
+type field struct{}
+
+func (f field) header() string { return "FIELD" }
+func (f field) value(output *Output) string {
+	if output.Field == "" {
+		return "n/a"
+	}
+	return output.Field
+}
+
 // replacement is the output replacement apiVersion
 type replacement struct{}
 
@@ -151,6 +211,68 @@ func (rai replacementAvailableIn) value(output *Output) string {
 	return output.APIVersion.ReplacementAvailableIn
 }
 
+// status is SUPPRESSED if the output is an accepted finding
+type status struct{}
+
+func (s status) header() string { return "STATUS" }
+func (s status) value(output *Output) string {
+	if output.Suppressed {
+		return "SUPPRESSED"
+	}
+	return ""
+}
+
+// rule is the name of the rule that matched, if the output is for a rule
+type rule struct{}
+
+func (r rule) header() string { return "RULE" }
+func (r rule) value(output *Output) string {
+	if output.Rule == "" {
+		return "n/a"
+	}
+	return output.Rule
+}
+
+// message is the message of the rule that matched
+type message struct{}
+
+func (m message) header() string              { return "MESSAGE" }
+func (m message) value(output *Output) string { return output.Message }
+
+// withRules adds the rule and message columns after the others if there are outputs of rules
+func (instance *Instance) withRules(columns columnList) columnList {
+	for _, output := range instance.Outputs {
+		if output.Rule != "" {
+			columns[len(columns)] = new(rule)
+			columns[len(columns)] = new(message)
+			break
+		}
+	}
+	return columns
+}
+
+// change is how the output changed since a previous report
+type change struct{}
+
+func (c change) header() string              { return "CHANGE" }
+func (c change) value(output *Output) string { return string(output.Change) }
+
+// withChange adds the change column after the others if the outputs were compared to a previous report
+func (instance *Instance) withChange(columns columnList) columnList {
+	if instance.compared {
+		columns[len(columns)] = new(change)
+	}
+	return columns
+}
+
+// withStatus adds the status column after the others if suppressed outputs can be shown
+func (instance *Instance) withStatus(columns columnList) columnList {
+	if instance.Baseline != nil || instance.ShowSuppressed {
+		columns[len(columns)] = new(status)
+	}
+	return columns
+}
+
 // normalColumns returns the list of columns for -onormal
 func (instance *Instance) normalColumns() columnList {
 	columnList := columnList{
@@ -162,7 +284,7 @@ func (instance *Instance) normalColumns() columnList {
 		5: new(deprecated),
 		6: new(replacementAvailable),
 	}
-	return columnList
+	return instance.withChange(instance.withStatus(instance.withRules(columnList)))
 }
 
 // wideColumns returns the list of columns for -owide
@@ -181,7 +303,7 @@ func (instance *Instance) wideColumns() columnList {
 		10: new(replacementAvailableIn),
 		11. new(typeColumn),
 	}
-	return columnList
+	return instance.withChange(instance.withStatus(instance.withRules(columnList)))
 }
 
 // customColumns returns a custom list of columns based on names
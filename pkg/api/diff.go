@@ -0,0 +1,114 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Change is how a finding changed since a previous report
+type Change string
+
+const (
+	// ChangeNew is a finding that is not in the previous report
+	ChangeNew Change = "NEW"
+	// ChangeFixed is a finding of the previous report that is no longer found
+	ChangeFixed Change = "FIXED"
+	// ChangeUnchanged is a finding that is in both reports
+	ChangeUnchanged Change = "UNCHANGED"
+)
+
+// findingIdentity is what makes two outputs the same finding across reports. Lines and
+// columns are left out, since they move whenever a file is edited.
+func findingIdentity(output *Output) string {
+	var kind, apiVersion string
+	if output.APIVersion != nil {
+		kind = output.APIVersion.Kind
+		apiVersion = output.APIVersion.Name
+	}
+	return strings.Join([]string{
+		output.Rule,
+		output.FilePath,
+		output.Namespace,
+		output.Name,
+		kind,
+		apiVersion,
+		output.Field,
+	}, "\x00")
+}
+
+// Compare replaces the outputs of the instance with how they changed since the outputs of
+// previous: outputs that are not in previous are NEW, outputs in both are UNCHANGED and
+// outputs of previous that are no longer found are added as FIXED. The outputs are expected
+// to be filtered already, so DisplayOutput does not filter them again, and only NEW
+// outputs count towards the return code.
+func (instance *Instance) Compare(previous *Instance) {
+	remaining := map[string]int{}
+	for _, output := range previous.Outputs {
+		remaining[findingIdentity(output)]++
+	}
+	var outputs []*Output
+	for _, output := range instance.Outputs {
+		identity := findingIdentity(output)
+		if remaining[identity] > 0 {
+			remaining[identity]--
+			output.Change = ChangeUnchanged
+		} else {
+			output.Change = ChangeNew
+		}
+		outputs = append(outputs, output)
+	}
+	for _, output := range previous.Outputs {
+		identity := findingIdentity(output)
+		if remaining[identity] == 0 {
+			continue
+		}
+		remaining[identity]--
+		fixed := *output
+		fixed.Change = ChangeFixed
+		outputs = append(outputs, &fixed)
+	}
+	instance.Outputs = outputs
+	instance.compared = true
+}
+
+// ChangeSummary returns a line for the footer with the number of new, fixed and unchanged
+// outputs, or an empty string if the instance was not compared to a previous report
+func (instance *Instance) ChangeSummary() string {
+	if !instance.compared {
+		return ""
+	}
+	counts := map[Change]int{}
+	for _, output := range instance.Outputs {
+		counts[output.Change]++
+	}
+	return fmt.Sprintf("%d new, %d fixed, %d unchanged", counts[ChangeNew], counts[ChangeFixed], counts[ChangeUnchanged])
+}
+
+// newOutputs returns the outputs to count towards the return code, which are the NEW
+// outputs if the instance was compared to a previous report
+func (instance *Instance) newOutputs() []*Output {
+	if !instance.compared {
+		return instance.Outputs
+	}
+	var outputs []*Output
+	for _, output := range instance.Outputs {
+		if output.Change == ChangeNew {
+			outputs = append(outputs, output)
+		}
+	}
+	return outputs
+}
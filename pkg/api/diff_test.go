@@ -0,0 +1,78 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstance_Compare(t *testing.T) {
+	ingress := &Version{Name: "extensions/v1beta1", Kind: "Ingress", DeprecatedIn: "v1.14.0", RemovedIn: "v1.22.0", ReplacementAvailableIn: "v1.19.0", Component: "k8s"}
+	psp := &Version{Name: "policy/v1beta1", Kind: "PodSecurityPolicy", DeprecatedIn: "v1.21.0", RemovedIn: "v1.25.0", ReplacementAvailableIn: "v1.21.0", Component: "k8s"}
+	deployment := &Version{Name: "apps/v1beta1", Kind: "Deployment", DeprecatedIn: "v1.9.0", RemovedIn: "v1.16.0", ReplacementAvailableIn: "v1.9.0", Component: "k8s"}
+
+	previous := &Instance{Outputs: []*Output{
+		{Name: "web", Namespace: "default", FilePath: "ingress.yaml", Line: 1, APIVersion: ingress},
+		{Name: "restricted", FilePath: "psp.yaml", APIVersion: psp},
+		{Name: "api", FilePath: "deploy.yaml", APIVersion: deployment},
+		{Name: "api", FilePath: "deploy.yaml", APIVersion: deployment},
+	}}
+	current := &Instance{
+		Outputs: []*Output{
+			{Name: "web", Namespace: "default", FilePath: "ingress.yaml", Line: 12, APIVersion: ingress},
+			{Name: "api", FilePath: "deploy.yaml", APIVersion: deployment},
+			{Name: "web", Namespace: "other", FilePath: "ingress.yaml", APIVersion: ingress},
+		},
+		TargetVersions: map[string]string{"k8s": "v1.25.0"},
+	}
+	assert.Equal(t, 3, current.GetReturnCode())
+	assert.Equal(t, "", current.ChangeSummary())
+
+	current.Compare(previous)
+	var got [][2]string
+	for _, output := range current.Outputs {
+		got = append(got, [2]string{output.Namespace + "/" + output.Name, string(output.Change)})
+	}
+	assert.Equal(t, [][2]string{
+		{"default/web", "UNCHANGED"},
+		{"/api", "UNCHANGED"},
+		{"other/web", "NEW"},
+		{"/restricted", "FIXED"},
+		{"/api", "FIXED"},
+	}, got)
+	assert.Equal(t, 12, current.Outputs[0].Line)
+	assert.Equal(t, ChangeUnchanged, current.Outputs[0].Change)
+	assert.Equal(t, Change(""), previous.Outputs[1].Change)
+	assert.Equal(t, "1 new, 2 fixed, 2 unchanged", current.ChangeSummary())
+	assert.Equal(t, 3, current.GetReturnCode())
+
+	current.Outputs[2].Suppressed = true
+	assert.Equal(t, 0, current.GetReturnCode())
+}
+
+func Test_findingIdentity(t *testing.T) {
+	version := &Version{Name: "extensions/v1beta1", Kind: "Ingress"}
+	output := &Output{Name: "web", Namespace: "default", FilePath: "ingress.yaml", Line: 1, Column: 1, APIVersion: version}
+	moved := &Output{Name: "web", Namespace: "default", FilePath: "ingress.yaml", Line: 20, Column: 3, APIVersion: version}
+	field := &Output{Name: "web", Namespace: "default", FilePath: "ingress.yaml", APIVersion: version, Field: "spec.backend"}
+	rule := &Output{Name: "web", Namespace: "default", FilePath: "ingress.yaml", APIVersion: version, Rule: "ingress-class-annotation"}
+
+	assert.Equal(t, findingIdentity(output), findingIdentity(moved))
+	assert.NotEqual(t, findingIdentity(output), findingIdentity(field))
+	assert.NotEqual(t, findingIdentity(output), findingIdentity(rule))
+	assert.NotEqual(t, findingIdentity(output), findingIdentity(&Output{Name: "web", Namespace: "default"}))
+}
@@ -0,0 +1,183 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/klog/v2"
+)
+
+// Field is a deprecated field within objects of a specific apiVersion and kind
+type Field struct {
+	// Path is a JSONPath-like path to the field, such as spec.template.spec.serviceAccount.
+	// Sequences can be traversed with [*] or indexed with [n].
+	Path string `json:"path" yaml:"path"`
+	// DeprecatedIn is the version the field is deprecated in
+	// an empty string indicates that the field is not deprecated
+	DeprecatedIn string `json:"deprecated-in" yaml:"deprecated-in"`
+	// RemovedIn is the version the field was removed in
+	// An empty string indicates that the field has not been removed yet
+	RemovedIn string `json:"removed-in" yaml:"removed-in"`
+	// Replacement is the path of the field that replaces this one
+	Replacement string `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+	// ReplacementAvailableIn is the version in which the replacement field is available
+	ReplacementAvailableIn string `json:"replacement-available-in,omitempty" yaml:"replacement-available-in,omitempty"`
+	// Component overrides the component of the parent version if set
+	Component string `json:"component,omitempty" yaml:"component,omitempty"`
+}
+
+// version returns a Version describing the field, so that field findings are
+// filtered and counted the same way as apiVersion findings
+func (f Field) version(parent *Version) *Version {
+	component := f.Component
+	if component == "" {
+		component = parent.Component
+	}
+	return &Version{
+		Name:                   parent.Name,
+		Kind:                   parent.Kind,
+		DeprecatedIn:           f.DeprecatedIn,
+		RemovedIn:              f.RemovedIn,
+		ReplacementAPI:         f.Replacement,
+		ReplacementAvailableIn: f.ReplacementAvailableIn,
+		Component:              component,
+	}
+}
+
+// checkFields returns an output for each of the fields that is set in the manifest
+func checkFields(m manifest, parent *Version, fields []Field) []*Output {
+	var outputs []*Output
+	for _, field := range fields {
+		key := findField(m.node, field.Path)
+		if key == nil {
+			continue
+		}
+		klog.V(5).Infof("found deprecated field %s in %s %s", field.Path, parent.Kind, m.stub.Metadata.Name)
+		outputs = append(outputs, &Output{
+			Name:       m.stub.Metadata.Name,
+			Namespace:  m.stub.Metadata.Namespace,
+			APIVersion: field.version(parent),
+			Field:      field.Path,
+			Document:   m.stub.Document,
+			Line:       key.Line,
+			Column:     key.Column,
+		})
+	}
+	return outputs
+}
+
+// findField returns the key node of the first field matching path, or nil if it is not set
+func findField(node *yaml.Node, path string) *yaml.Node {
+	segments := parseFieldPath(path)
+	if len(segments) == 0 {
+		return nil
+	}
+	return matchField(documentContent(node), segments)
+}
+
+// fieldSegment is a single step of a field path, a key optionally followed by a sequence index
+type fieldSegment struct {
+	key string
+	// index is -1 when every element of a sequence matches, -2 when no index is given
+	index int
+}
+
+const (
+	anyIndex = -1
+	noIndex  = -2
+)
+
+// parseFieldPath splits a path like $.spec.rules[*].http into segments.
+// A leading $ or . is optional.
+func parseFieldPath(path string) []fieldSegment {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return nil
+	}
+	var segments []fieldSegment
+	for _, part := range strings.Split(path, ".") {
+		key := part
+		indexes := []int{}
+		for strings.HasSuffix(key, "]") {
+			open := strings.LastIndex(key, "[")
+			if open < 0 {
+				break
+			}
+			index := key[open+1 : len(key)-1]
+			key = key[:open]
+			switch index {
+			case "*", "":
+				indexes = append([]int{anyIndex}, indexes...)
+			default:
+				n, err := strconv.Atoi(index)
+				if err != nil {
+					klog.V(3).Infof("invalid index %q in field path %s", index, path)
+					return nil
+				}
+				indexes = append([]int{n}, indexes...)
+			}
+		}
+		if key != "" {
+			segments = append(segments, fieldSegment{key: key, index: noIndex})
+		}
+		for _, index := range indexes {
+			segments = append(segments, fieldSegment{index: index})
+		}
+	}
+	return segments
+}
+
+func matchField(node *yaml.Node, segments []fieldSegment) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+	segment := segments[0]
+	rest := segments[1:]
+	if segment.index == noIndex {
+		key := mappingKey(node, segment.key)
+		if key == nil {
+			return nil
+		}
+		value := mappingValue(node, segment.key)
+		if len(rest) == 0 {
+			if value.Tag == "!!null" {
+				return nil
+			}
+			return key
+		}
+		return matchField(value, rest)
+	}
+	if node.Kind != yaml.SequenceNode {
+		return nil
+	}
+	if segment.index >= 0 {
+		if segment.index >= len(node.Content) || len(rest) == 0 {
+			return nil
+		}
+		return matchField(node.Content[segment.index], rest)
+	}
+	if len(rest) == 0 {
+		return nil
+	}
+	for _, element := range node.Content {
+		if found := matchField(element, rest); found != nil {
+			return found
+		}
+	}
+	return nil
+}
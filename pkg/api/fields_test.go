@@ -0,0 +1,186 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+var testVersionWithFields = Version{
+	Name:      "apps/v1",
+	Kind:      "Deployment",
+	Component: "k8s",
+	Fields: []Field{
+		{
+			Path:         "spec.template.spec.serviceAccount",
+			DeprecatedIn: "v1.0.0",
+			Replacement:  "spec.template.spec.serviceAccountName",
+		},
+		{
+			Path:         "spec.template.spec.containers[*].securityContext.seLinuxOptions",
+			DeprecatedIn: "v1.5.0",
+			RemovedIn:    "v1.9.0",
+			Component:    "custom",
+		},
+	},
+}
+
+func Test_parseFieldPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want []fieldSegment
+	}{
+		{
+			name: "empty",
+			path: "",
+			want: nil,
+		},
+		{
+			name: "dotted",
+			path: "spec.backend",
+			want: []fieldSegment{{key: "spec", index: noIndex}, {key: "backend", index: noIndex}},
+		},
+		{
+			name: "jsonpath prefix and indexes",
+			path: "$.spec.rules[*].paths[1].backend",
+			want: []fieldSegment{
+				{key: "spec", index: noIndex},
+				{key: "rules", index: noIndex},
+				{index: anyIndex},
+				{key: "paths", index: noIndex},
+				{index: 1},
+				{key: "backend", index: noIndex},
+			},
+		},
+		{
+			name: "invalid index",
+			path: "spec.rules[x].host",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseFieldPath(tt.path))
+		})
+	}
+}
+
+func Test_findField(t *testing.T) {
+	data := `spec:
+  backend:
+    serviceName: foo
+  empty: null
+  rules:
+  - host: one
+  - host: two
+    http:
+      paths:
+      - backend:
+          serviceName: bar
+`
+	var node yaml.Node
+	assert.NoError(t, yaml.Unmarshal([]byte(data), &node))
+
+	tests := []struct {
+		name     string
+		path     string
+		wantLine int
+	}{
+		{name: "top level", path: "spec.backend", wantLine: 2},
+		{name: "null is unset", path: "spec.empty", wantLine: 0},
+		{name: "missing", path: "spec.defaultBackend", wantLine: 0},
+		{name: "wildcard", path: "spec.rules[*].http.paths[*].backend.serviceName", wantLine: 11},
+		{name: "index", path: "spec.rules[1].host", wantLine: 7},
+		{name: "index out of range", path: "spec.rules[2].host", wantLine: 0},
+		{name: "not a sequence", path: "spec.backend[*].serviceName", wantLine: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findField(&node, tt.path)
+			if tt.wantLine == 0 {
+				assert.Nil(t, got)
+				return
+			}
+			assert.NotNil(t, got)
+			assert.Equal(t, tt.wantLine, got.Line)
+		})
+	}
+}
+
+func Test_IsVersioned_fields(t *testing.T) {
+	instance := Instance{
+		DeprecatedVersions: []Version{testVersionWithFields},
+	}
+	data := []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: foo
+spec:
+  template:
+    spec:
+      serviceAccount: foo
+      containers:
+      - name: one
+      - name: two
+        securityContext:
+          seLinuxOptions: {}
+`)
+	got, err := instance.IsVersioned(data)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Output{
+		{
+			Name:       "foo",
+			Document:   1,
+			Line:       1,
+			Column:     1,
+			APIVersion: &Version{Name: "apps/v1", Kind: "Deployment", Component: "k8s"},
+		},
+		{
+			Name:     "foo",
+			Field:    "spec.template.spec.serviceAccount",
+			Document: 1,
+			Line:     8,
+			APIVersion: &Version{
+				Name:           "apps/v1",
+				Kind:           "Deployment",
+				DeprecatedIn:   "v1.0.0",
+				ReplacementAPI: "spec.template.spec.serviceAccountName",
+				Component:      "k8s",
+			},
+			Column: 7,
+		},
+		{
+			Name:     "foo",
+			Field:    "spec.template.spec.containers[*].securityContext.seLinuxOptions",
+			Document: 1,
+			Line:     13,
+			Column:   11,
+			APIVersion: &Version{
+				Name:         "apps/v1",
+				Kind:         "Deployment",
+				DeprecatedIn: "v1.5.0",
+				RemovedIn:    "v1.9.0",
+				Component:    "custom",
+			},
+		},
+	}, got)
+
+	// the catalog entry must not be modified by the lookup
+	assert.Len(t, instance.DeprecatedVersions[0].Fields, 2)
+}
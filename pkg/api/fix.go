@@ -0,0 +1,412 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/klog/v2"
+)
+
+// Conversion migrates the schema of an object whose apiVersion is being rewritten.
+// It receives the mapping node of the object and modifies it in place.
+type Conversion func(object *yaml.Node) error
+
+// conversions are keyed by kind and the apiVersion the object is being rewritten to
+var conversions = map[string]Conversion{
+	conversionKey("Ingress", "networking.k8s.io/v1"): convertIngressV1,
+	conversionKey("Deployment", "apps/v1"):           convertSelectorRequired,
+	conversionKey("DaemonSet", "apps/v1"):            convertSelectorRequired,
+	conversionKey("ReplicaSet", "apps/v1"):           convertSelectorRequired,
+	conversionKey("StatefulSet", "apps/v1"):          convertSelectorRequired,
+}
+
+func conversionKey(kind string, apiVersion string) string {
+	return kind + "|" + apiVersion
+}
+
+// RegisterConversion adds a conversion that runs whenever an object of the given kind
+// is rewritten to apiVersion. It replaces any conversion already registered for the pair.
+func RegisterConversion(kind string, apiVersion string, conversion Conversion) {
+	conversions[conversionKey(kind, apiVersion)] = conversion
+}
+
+// FixVersions rewrites the apiVersion of every object in data that is deprecated or removed
+// in the target versions, provided its replacement is available in the target versions.
+// It returns the rewritten data and an output for every object that was changed.
+// Documents without changes are returned byte-for-byte, changed documents are
+// round-tripped through yaml.v3 so comments and key order are kept. Block sequences
+// that are not indented under their key in the input are not indented in the output either.
+func (instance *Instance) FixVersions(data []byte) ([]byte, []*Output, error) {
+	if json.Valid(data) {
+		return nil, nil, fmt.Errorf("fixing json manifests is not supported")
+	}
+	var outputs []*Output
+	var fixed bytes.Buffer
+	indent := detectIndent(data)
+	for document, segment := range splitDocuments(data) {
+		fixed.WriteString(segment.separator)
+		var node yaml.Node
+		err := yaml.Unmarshal([]byte(segment.body), &node)
+		if err != nil {
+			return nil, nil, err
+		}
+		if node.Kind != yaml.DocumentNode || len(node.Content) == 0 {
+			fixed.WriteString(segment.body)
+			continue
+		}
+		root := node.Content[0]
+		objects := []*yaml.Node{root}
+		if items := mappingValue(root, "items"); items != nil && items.Kind == yaml.SequenceNode {
+			objects = items.Content
+		}
+		var changed bool
+		for _, object := range objects {
+			output, err := instance.fixObject(object)
+			if err != nil {
+				return nil, nil, err
+			}
+			if output == nil {
+				continue
+			}
+			changed = true
+			output.Document = document + 1
+			output.Line += segment.offset
+			outputs = append(outputs, output)
+			klog.V(3).Infof("rewrote %s %s in document %d to %s", output.APIVersion.Kind, output.APIVersion.Name, document, output.APIVersion.ReplacementAPI)
+		}
+		if !changed {
+			fixed.WriteString(segment.body)
+			continue
+		}
+		var encoded bytes.Buffer
+		encoder := yaml.NewEncoder(&encoded)
+		encoder.SetIndent(indent)
+		err = encoder.Encode(&node)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = encoder.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		if hasCompactSequences(root) {
+			fixed.WriteString(compactSequences(encoded.String(), indent))
+			continue
+		}
+		fixed.Write(encoded.Bytes())
+	}
+	return fixed.Bytes(), outputs, nil
+}
+
+// fixObject rewrites the apiVersion of a single object mapping and runs any conversion
+// registered for it. It returns nil if the object was left alone.
+func (instance *Instance) fixObject(object *yaml.Node) (*Output, error) {
+	apiVersionKey := mappingKey(object, "apiVersion")
+	apiVersion := mappingValue(object, "apiVersion")
+	if apiVersion == nil || apiVersion.Kind != yaml.ScalarNode {
+		return nil, nil
+	}
+	stub := &Stub{APIVersion: apiVersion.Value}
+	if kind := mappingValue(object, "kind"); kind != nil {
+		stub.Kind = kind.Value
+	}
+	if metadata := mappingValue(object, "metadata"); metadata != nil {
+		_ = metadata.Decode(&stub.Metadata)
+	}
+	version := instance.checkVersion(stub)
+	if version == nil || !instance.isFixable(version) {
+		return nil, nil
+	}
+	if conversion, ok := conversions[conversionKey(stub.Kind, version.ReplacementAPI)]; ok {
+		err := conversion(object)
+		if err != nil {
+			return nil, fmt.Errorf("could not convert %s %s to %s: %w", stub.Kind, stub.Metadata.Name, version.ReplacementAPI, err)
+		}
+	}
+	apiVersion.Value = version.ReplacementAPI
+	return &Output{
+		Name:       stub.Metadata.Name,
+		Namespace:  stub.Metadata.Namespace,
+		APIVersion: version,
+		Line:       apiVersionKey.Line,
+		Column:     apiVersionKey.Column,
+	}, nil
+}
+
+// isFixable returns true if the version is flagged for the target versions and
+// its replacement can be used in them
+func (instance *Instance) isFixable(version *Version) bool {
+	if version.ReplacementAPI == "" || !StringInSlice(version.Component, instance.Components) {
+		return false
+	}
+	if !version.isDeprecatedIn(instance.TargetVersions) && !version.isRemovedIn(instance.TargetVersions) {
+		return false
+	}
+	return version.isReplacementAvailableIn(instance.TargetVersions)
+}
+
+// documentSegment is a single document of a multi-document yaml stream
+// along with the separator line that preceded it
+type documentSegment struct {
+	separator string
+	body      string
+	// offset is the number of lines in the stream before the body
+	offset int
+}
+
+// splitDocuments splits a yaml stream on document separators, keeping the separators
+// so that the stream can be reassembled unchanged
+func splitDocuments(data []byte) []documentSegment {
+	var segments []documentSegment
+	current := documentSegment{}
+	for i, line := range strings.SplitAfter(string(data), "\n") {
+		if isDocumentSeparator(line) {
+			if current.separator != "" || current.body != "" {
+				segments = append(segments, current)
+			}
+			current = documentSegment{separator: line, offset: i + 1}
+			continue
+		}
+		current.body += line
+	}
+	return append(segments, current)
+}
+
+func isDocumentSeparator(line string) bool {
+	if !strings.HasPrefix(line, "---") {
+		return false
+	}
+	rest := strings.TrimSpace(strings.TrimPrefix(line, "---"))
+	return rest == "" || strings.HasPrefix(rest, "#")
+}
+
+// detectIndent returns the indentation width of the first indented mapping key, defaulting to two spaces
+func detectIndent(data []byte) int {
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		indent := len(line) - len(trimmed)
+		if indent == 0 || trimmed == "" || strings.HasPrefix(trimmed, "-") || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		return indent
+	}
+	return 2
+}
+
+// hasCompactSequences returns true if a block sequence in node starts at the same column
+// as the key it is the value of. yaml.v3 always indents such sequences when encoding.
+func hasCompactSequences(node *yaml.Node) bool {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if value.Kind == yaml.SequenceNode && value.Style&yaml.FlowStyle == 0 && value.Line > 0 && value.Column == key.Column {
+				return true
+			}
+		}
+	}
+	for _, child := range node.Content {
+		if hasCompactSequences(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// blockScalarHeader matches the end of a line that starts a literal or folded block scalar
+var blockScalarHeader = regexp.MustCompile(`(^|\s)[|>][1-9]?[-+]?[1-9]?(\s+#.*)?$`)
+
+// compactSequences removes the indentation yaml.v3 adds to a block sequence under a mapping
+// key, so that "key:\n  - item" is written as "key:\n- item". The contents of block scalars
+// are moved along with their parent but never treated as sequences.
+func compactSequences(data string, indent int) string {
+	// sequences holds the column of the key of every sequence the current line is in
+	var sequences []int
+	// blockScalar is the column a line must be indented past to be in the current block scalar
+	blockScalar := -1
+	var compacted strings.Builder
+	lines := strings.SplitAfter(data, "\n")
+	for i, line := range lines {
+		content := strings.TrimLeft(line, " ")
+		column := len(line) - len(content)
+		blank := strings.TrimSpace(line) == ""
+		if !blank {
+			for len(sequences) > 0 && column <= sequences[len(sequences)-1] {
+				sequences = sequences[:len(sequences)-1]
+			}
+		}
+		shift := indent * len(sequences)
+		if shift > column {
+			shift = column
+		}
+		compacted.WriteString(line[shift:])
+		if blockScalar >= 0 && (blank || column > blockScalar) {
+			continue
+		}
+		blockScalar = -1
+
+		// the key of a sequence item starts after its dashes
+		dash, key := -1, column
+		for strings.HasPrefix(line[key:], "- ") {
+			dash = key
+			key += 2
+			for key < len(line) && line[key] == ' ' {
+				key++
+			}
+		}
+		value := strings.TrimRight(line[key:], "\n")
+		switch {
+		case blockScalarHeader.MatchString(value):
+			blockScalar = key
+			if dash >= 0 && (strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">")) {
+				blockScalar = dash
+			}
+		case strings.HasSuffix(value, ":") && startsSequence(lines[i+1:], key+indent):
+			sequences = append(sequences, key)
+		}
+	}
+	return compacted.String()
+}
+
+// startsSequence returns true if the first line with content in lines is a sequence item at column
+func startsSequence(lines []string, column int) bool {
+	for _, line := range lines {
+		content := strings.TrimLeft(line, " ")
+		if strings.TrimSpace(content) == "" || strings.HasPrefix(content, "#") {
+			continue
+		}
+		return len(line)-len(content) == column && (strings.HasPrefix(content, "- ") || strings.TrimSpace(content) == "-")
+	}
+	return false
+}
+
+// convertIngressV1 migrates the backends of an extensions/v1beta1 or networking.k8s.io/v1beta1
+// Ingress to the networking.k8s.io/v1 schema
+func convertIngressV1(object *yaml.Node) error {
+	spec := mappingValue(object, "spec")
+	if spec == nil {
+		return nil
+	}
+	if key := mappingKey(spec, "backend"); key != nil {
+		key.Value = "defaultBackend"
+		convertIngressBackend(mappingValue(spec, "defaultBackend"))
+	}
+	rules := mappingValue(spec, "rules")
+	if rules == nil || rules.Kind != yaml.SequenceNode {
+		return nil
+	}
+	for _, rule := range rules.Content {
+		paths := mappingValue(mappingValue(rule, "http"), "paths")
+		if paths == nil || paths.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, path := range paths.Content {
+			convertIngressBackend(mappingValue(path, "backend"))
+			if mappingValue(path, "pathType") == nil && path.Kind == yaml.MappingNode {
+				setMappingValue(path, "pathType", scalarNode("ImplementationSpecific"))
+			}
+		}
+	}
+	return nil
+}
+
+// convertIngressBackend turns serviceName/servicePort into service.name and service.port
+func convertIngressBackend(backend *yaml.Node) {
+	serviceName := removeMappingKey(backend, "serviceName")
+	servicePort := removeMappingKey(backend, "servicePort")
+	if serviceName == nil && servicePort == nil {
+		return
+	}
+	service := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if serviceName != nil {
+		setMappingValue(service, "name", serviceName)
+	}
+	if servicePort != nil {
+		port := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		if servicePort.ShortTag() == "!!int" {
+			setMappingValue(port, "number", servicePort)
+		} else {
+			setMappingValue(port, "name", servicePort)
+		}
+		setMappingValue(service, "port", port)
+	}
+	setMappingValue(backend, "service", service)
+}
+
+// convertSelectorRequired adds spec.selector from the pod template labels,
+// since apps/v1 workloads no longer default it
+func convertSelectorRequired(object *yaml.Node) error {
+	spec := mappingValue(object, "spec")
+	if spec == nil || mappingValue(spec, "selector") != nil {
+		return nil
+	}
+	labels := mappingValue(mappingValue(mappingValue(spec, "template"), "metadata"), "labels")
+	if labels == nil || labels.Kind != yaml.MappingNode || len(labels.Content) == 0 {
+		return fmt.Errorf("spec.selector is required and cannot be derived without spec.template.metadata.labels")
+	}
+	selector := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	setMappingValue(selector, "matchLabels", copyNode(labels))
+	setMappingValue(spec, "selector", selector)
+	return nil
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// setMappingValue replaces the value for key in a mapping, appending the key if it is missing
+func setMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = value
+			return
+		}
+	}
+	node.Content = append(node.Content, scalarNode(key), value)
+}
+
+// removeMappingKey removes key from a mapping and returns its value
+func removeMappingKey(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			value := node.Content[i+1]
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return value
+		}
+	}
+	return nil
+}
+
+// copyNode returns a deep copy of node without its comments
+func copyNode(node *yaml.Node) *yaml.Node {
+	c := *node
+	c.HeadComment, c.LineComment, c.FootComment = "", "", ""
+	c.Content = nil
+	for _, child := range node.Content {
+		c.Content = append(c.Content, copyNode(child))
+	}
+	return &c
+}
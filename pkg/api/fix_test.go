@@ -0,0 +1,276 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var fixInstance = Instance{
+	TargetVersions: map[string]string{
+		"k8s": "v1.22.0",
+	},
+	Components: []string{"k8s"},
+	DeprecatedVersions: []Version{
+		{
+			Name:                   "extensions/v1beta1",
+			Kind:                   "Deployment",
+			DeprecatedIn:           "v1.9.0",
+			RemovedIn:              "v1.16.0",
+			ReplacementAPI:         "apps/v1",
+			ReplacementAvailableIn: "v1.9.0",
+			Component:              "k8s",
+		},
+		{
+			Name:                   "extensions/v1beta1",
+			Kind:                   "Ingress",
+			DeprecatedIn:           "v1.14.0",
+			RemovedIn:              "v1.22.0",
+			ReplacementAPI:         "networking.k8s.io/v1",
+			ReplacementAvailableIn: "v1.19.0",
+			Component:              "k8s",
+		},
+		{
+			Name:                   "policy/v1beta1",
+			Kind:                   "PodDisruptionBudget",
+			DeprecatedIn:           "v1.21.0",
+			RemovedIn:              "v1.25.0",
+			ReplacementAPI:         "policy/v1",
+			ReplacementAvailableIn: "v1.23.0",
+			Component:              "k8s",
+		},
+	},
+}
+
+func TestInstance_FixVersions(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		want      string
+		wantLines []int
+		wantErr   bool
+	}{
+		{
+			name:      "nothing to fix",
+			data:      "# keep me\napiVersion: apps/v1\nkind: Deployment\n",
+			want:      "# keep me\napiVersion: apps/v1\nkind: Deployment\n",
+			wantLines: nil,
+		},
+		{
+			name:      "replacement not available in target",
+			data:      "apiVersion: policy/v1beta1\nkind: PodDisruptionBudget\n",
+			want:      "apiVersion: policy/v1beta1\nkind: PodDisruptionBudget\n",
+			wantLines: nil,
+		},
+		{
+			name: "deployment gets apiVersion and selector",
+			data: `# a comment
+apiVersion: extensions/v1beta1 # old
+kind: Deployment
+metadata:
+    name: utilities
+spec:
+    template:
+        metadata:
+            labels:
+                app: utilities
+`,
+			want: `# a comment
+apiVersion: apps/v1 # old
+kind: Deployment
+metadata:
+    name: utilities
+spec:
+    template:
+        metadata:
+            labels:
+                app: utilities
+    selector:
+        matchLabels:
+            app: utilities
+`,
+			wantLines: []int{2},
+		},
+		{
+			name: "only the changed document is rewritten",
+			data: `apiVersion: v1
+kind:    Service
+---
+apiVersion: extensions/v1beta1
+kind: Ingress
+spec:
+  backend:
+    serviceName: default
+    servicePort: 80
+  rules:
+  - http:
+      paths:
+      - path: /
+        backend:
+          serviceName: web
+          servicePort: http
+`,
+			want: `apiVersion: v1
+kind:    Service
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+spec:
+  defaultBackend:
+    service:
+      name: default
+      port:
+        number: 80
+  rules:
+  - http:
+      paths:
+      - path: /
+        backend:
+          service:
+            name: web
+            port:
+              name: http
+        pathType: ImplementationSpecific
+`,
+			wantLines: []int{4},
+		},
+		{
+			name: "indented sequences stay indented",
+			data: `apiVersion: extensions/v1beta1
+kind: Deployment
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    spec:
+      containers:
+        - name: web
+          ports:
+            - containerPort: 80
+`,
+			want: `apiVersion: apps/v1
+kind: Deployment
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    spec:
+      containers:
+        - name: web
+          ports:
+            - containerPort: 80
+`,
+			wantLines: []int{1},
+		},
+		{
+			name: "compact sequences stay compact",
+			data: `apiVersion: extensions/v1beta1
+kind: Deployment
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    spec:
+      containers:
+      - name: web
+        args:
+        - --config
+        - |
+          items:
+            - kept as is
+        env:
+        - name: CONFIG
+          value: |
+            list:
+              - kept as is
+`,
+			want: `apiVersion: apps/v1
+kind: Deployment
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    spec:
+      containers:
+      - name: web
+        args:
+        - --config
+        - |
+          items:
+            - kept as is
+        env:
+        - name: CONFIG
+          value: |
+            list:
+              - kept as is
+`,
+			wantLines: []int{1},
+		},
+		{
+			name:      "list items are fixed individually",
+			data:      "apiVersion: v1\nkind: List\nitems:\n- apiVersion: apps/v1\n  kind: Deployment\n- apiVersion: extensions/v1beta1\n  kind: Deployment\n  spec:\n    selector: {}\n",
+			want:      "apiVersion: v1\nkind: List\nitems:\n- apiVersion: apps/v1\n  kind: Deployment\n- apiVersion: apps/v1\n  kind: Deployment\n  spec:\n    selector: {}\n",
+			wantLines: []int{6},
+		},
+		{
+			name:    "selector cannot be derived",
+			data:    "apiVersion: extensions/v1beta1\nkind: Deployment\nspec:\n  replicas: 1\n",
+			wantErr: true,
+		},
+		{
+			name:    "json is not supported",
+			data:    `{"apiVersion": "extensions/v1beta1", "kind": "Deployment"}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, outputs, err := fixInstance.FixVersions([]byte(tt.data))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+			var lines []int
+			for _, output := range outputs {
+				lines = append(lines, output.Line)
+			}
+			assert.Equal(t, tt.wantLines, lines)
+		})
+	}
+}
+
+func Test_splitDocuments(t *testing.T) {
+	data := "---\na: b\n--- # second\nc: d\n---\n"
+	got := splitDocuments([]byte(data))
+	assert.Equal(t, []documentSegment{
+		{separator: "---\n", body: "a: b\n", offset: 1},
+		{separator: "--- # second\n", body: "c: d\n", offset: 3},
+		{separator: "---\n", body: "", offset: 5},
+	}, got)
+
+	var joined string
+	for _, segment := range got {
+		joined += segment.separator + segment.body
+	}
+	assert.Equal(t, data, joined)
+}
@@ -0,0 +1,362 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+	"k8s.io/klog/v2"
+)
+
+// ReleaseSpec holds the OpenAPI documents of a single Kubernetes release.
+// Documents may be a single OpenAPI v2 document, such as a dump of /openapi/v2,
+// or any number of OpenAPI v3 documents, such as the per group-version files in
+// the api/openapi-spec/v3 directory of the Kubernetes repository.
+type ReleaseSpec struct {
+	Version   string
+	Documents [][]byte
+}
+
+// groupVersionKind is the x-kubernetes-group-version-kind extension
+type groupVersionKind struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+func (gvk groupVersionKind) apiVersion() string {
+	if gvk.Group == "" {
+		return gvk.Version
+	}
+	return gvk.Group + "/" + gvk.Version
+}
+
+// openAPISchema is the part of a schema definition needed to find deprecations
+type openAPISchema struct {
+	Description          string             `json:"description"`
+	Deprecated           bool               `json:"deprecated"`
+	KubernetesDeprecated bool               `json:"x-kubernetes-deprecated"`
+	GroupVersionKinds    []groupVersionKind `json:"x-kubernetes-group-version-kind"`
+}
+
+// openAPIOperation is the part of a path operation needed to find served kinds
+type openAPIOperation struct {
+	Deprecated       bool              `json:"deprecated"`
+	Action           string            `json:"x-kubernetes-action"`
+	GroupVersionKind *groupVersionKind `json:"x-kubernetes-group-version-kind"`
+}
+
+// openAPIDocument covers both OpenAPI v2 (definitions) and v3 (components.schemas)
+type openAPIDocument struct {
+	Definitions map[string]openAPISchema `json:"definitions"`
+	Components  struct {
+		Schemas map[string]openAPISchema `json:"schemas"`
+	} `json:"components"`
+	Paths map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+// servedKind is the state of a group/version/kind in a single release
+type servedKind struct {
+	deprecated  bool
+	replacement string
+}
+
+var (
+	deprecatedPattern   = regexp.MustCompile(`(?i)\bdeprecated\b`)
+	deprecatedByPattern = regexp.MustCompile(`(?i)deprecated by ([a-z0-9.-]+/)?(v[0-9]+(?:(?:alpha|beta)[0-9]+)?)[/ ][A-Z]`)
+	kubeVersionPattern  = regexp.MustCompile(`^v([0-9]+)(?:(alpha|beta)([0-9]+))?$`)
+)
+
+// parseReleaseSpec returns the kinds served in a release. A kind is served if it has a
+// list operation. Documents without paths fall back to every schema with a single
+// group/version/kind.
+func parseReleaseSpec(release ReleaseSpec) (map[groupVersionKind]servedKind, error) {
+	schemas := map[groupVersionKind]openAPISchema{}
+	served := map[groupVersionKind]servedKind{}
+	var hasPaths bool
+	for i, data := range release.Documents {
+		document := openAPIDocument{}
+		err := json.Unmarshal(data, &document)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse document %d of %s: %s", i, release.Version, err.Error())
+		}
+		for _, definitions := range []map[string]openAPISchema{document.Definitions, document.Components.Schemas} {
+			for _, schema := range definitions {
+				// shared types such as DeleteOptions list every group they belong to
+				if len(schema.GroupVersionKinds) == 1 {
+					schemas[schema.GroupVersionKinds[0]] = schema
+				}
+			}
+		}
+		for _, path := range document.Paths {
+			for method, raw := range path {
+				if method == "parameters" {
+					continue
+				}
+				operation := openAPIOperation{}
+				if json.Unmarshal(raw, &operation) != nil || operation.GroupVersionKind == nil {
+					continue
+				}
+				hasPaths = true
+				if operation.Action != "list" {
+					continue
+				}
+				kind := served[*operation.GroupVersionKind]
+				kind.deprecated = kind.deprecated || operation.Deprecated
+				served[*operation.GroupVersionKind] = kind
+			}
+		}
+	}
+	if !hasPaths {
+		for gvk := range schemas {
+			if !isListKind(gvk.Kind) {
+				served[gvk] = servedKind{}
+			}
+		}
+	}
+	for gvk, kind := range served {
+		schema, ok := schemas[gvk]
+		if !ok {
+			continue
+		}
+		if schema.Deprecated || schema.KubernetesDeprecated || deprecatedPattern.MatchString(schema.Description) {
+			kind.deprecated = true
+		}
+		if match := deprecatedByPattern.FindStringSubmatch(schema.Description); match != nil {
+			kind.replacement = match[1] + match[2]
+		}
+		served[gvk] = kind
+	}
+	return served, nil
+}
+
+func isListKind(kind string) bool {
+	return strings.HasSuffix(kind, "List") && kind != "List"
+}
+
+// GenerateVersions infers deprecated versions from the OpenAPI documents of several releases.
+// A group/version/kind is deprecated in the first release whose schema or list operation
+// marks it deprecated, and removed in the first release after the last one that serves it.
+// Its replacement is taken from the deprecation notice if it names one, otherwise it is the
+// most stable version of the same kind served in the newest release, preferring the same group.
+// Only group/version/kinds that are deprecated or removed are returned.
+func GenerateVersions(releases []ReleaseSpec, component string) ([]Version, error) {
+	for _, release := range releases {
+		if !semver.IsValid(release.Version) {
+			return nil, fmt.Errorf("release versions must be valid semver with a leading 'v' - got %s", release.Version)
+		}
+	}
+	sorted := make([]ReleaseSpec, len(releases))
+	copy(sorted, releases)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return semver.Compare(sorted[i].Version, sorted[j].Version) < 0
+	})
+
+	served := make([]map[groupVersionKind]servedKind, len(sorted))
+	firstSeen := map[groupVersionKind]int{}
+	var all []groupVersionKind
+	for i, release := range sorted {
+		kinds, err := parseReleaseSpec(release)
+		if err != nil {
+			return nil, err
+		}
+		klog.V(3).Infof("found %d kinds in %s", len(kinds), release.Version)
+		served[i] = kinds
+		for gvk := range kinds {
+			if _, found := firstSeen[gvk]; !found {
+				firstSeen[gvk] = i
+				all = append(all, gvk)
+			}
+		}
+	}
+	if len(sorted) == 0 {
+		return nil, nil
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].apiVersion() != all[j].apiVersion() {
+			return all[i].apiVersion() < all[j].apiVersion()
+		}
+		return all[i].Kind < all[j].Kind
+	})
+
+	newest := served[len(served)-1]
+	var versions []Version
+	for _, gvk := range all {
+		version := Version{
+			Name:      gvk.apiVersion(),
+			Kind:      gvk.Kind,
+			Component: component,
+		}
+		lastSeen := firstSeen[gvk]
+		var replacement string
+		for i := firstSeen[gvk]; i < len(sorted); i++ {
+			kind, found := served[i][gvk]
+			if !found {
+				continue
+			}
+			lastSeen = i
+			if kind.deprecated && version.DeprecatedIn == "" {
+				version.DeprecatedIn = sorted[i].Version
+			}
+			if kind.replacement != "" {
+				replacement = kind.replacement
+			}
+		}
+		if lastSeen < len(sorted)-1 {
+			version.RemovedIn = sorted[lastSeen+1].Version
+		}
+		if version.DeprecatedIn == "" && version.RemovedIn == "" {
+			continue
+		}
+		if replacement == "" {
+			replacement = findReplacement(gvk, newest)
+		}
+		if replacement != "" && replacement != version.Name {
+			version.ReplacementAPI = replacement
+			group, groupVersion := splitAPIVersion(replacement)
+			if first, found := firstSeen[groupVersionKind{Group: group, Version: groupVersion, Kind: gvk.Kind}]; found {
+				version.ReplacementAvailableIn = sorted[first].Version
+			}
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+// GeneratedTargetVersions returns the target-versions of a generated versions file, which
+// targets the component at the newest release
+func GeneratedTargetVersions(releases []ReleaseSpec, component string) map[string]string {
+	newest := ""
+	for _, release := range releases {
+		if newest == "" || semver.Compare(release.Version, newest) > 0 {
+			newest = release.Version
+		}
+	}
+	if newest == "" {
+		return nil
+	}
+	return map[string]string{component: newest}
+}
+
+// findReplacement returns the apiVersion of the most stable, non-deprecated version of the
+// same kind in kinds, preferring the same group
+func findReplacement(deprecated groupVersionKind, kinds map[groupVersionKind]servedKind) string {
+	var best *groupVersionKind
+	for gvk, kind := range kinds {
+		gvk := gvk
+		if gvk.Kind != deprecated.Kind || gvk == deprecated || kind.deprecated {
+			continue
+		}
+		if best == nil {
+			best = &gvk
+			continue
+		}
+		sameGroup, bestSameGroup := gvk.Group == deprecated.Group, best.Group == deprecated.Group
+		if sameGroup != bestSameGroup {
+			if sameGroup {
+				best = &gvk
+			}
+			continue
+		}
+		if c := compareKubeVersions(gvk.Version, best.Version); c > 0 || (c == 0 && gvk.Group < best.Group) {
+			best = &gvk
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	return best.apiVersion()
+}
+
+// compareKubeVersions orders Kubernetes API versions such as v1, v2beta1 and v1alpha1.
+// GA versions rank above beta, beta above alpha, and higher numbers above lower ones.
+func compareKubeVersions(a string, b string) int {
+	aMajor, aStability, aMinor := parseKubeVersion(a)
+	bMajor, bStability, bMinor := parseKubeVersion(b)
+	switch {
+	case aStability != bStability:
+		return aStability - bStability
+	case aMajor != bMajor:
+		return aMajor - bMajor
+	default:
+		return aMinor - bMinor
+	}
+}
+
+func parseKubeVersion(version string) (major int, stability int, minor int) {
+	match := kubeVersionPattern.FindStringSubmatch(version)
+	if match == nil {
+		return 0, -1, 0
+	}
+	major, _ = strconv.Atoi(match[1])
+	minor, _ = strconv.Atoi(match[3])
+	switch match[2] {
+	case "alpha":
+		stability = 0
+	case "beta":
+		stability = 1
+	default:
+		stability = 2
+	}
+	return major, stability, minor
+}
+
+// splitAPIVersion splits an apiVersion into its group, empty for the core group, and version
+func splitAPIVersion(apiVersion string) (string, string) {
+	slash := strings.LastIndex(apiVersion, "/")
+	if slash < 0 {
+		return "", apiVersion
+	}
+	return apiVersion[:slash], apiVersion[slash+1:]
+}
+
+// MergeVersions merges generated versions into existing ones. Existing versions keep every
+// value they already have and only take values from the generated version they are missing.
+// Generated versions without an existing counterpart are appended.
+func MergeVersions(existing []Version, generated []Version) []Version {
+	merged := make([]Version, len(existing))
+	copy(merged, existing)
+	for _, version := range generated {
+		found := false
+		for i := range merged {
+			if !isDuplicate(merged[i], version) {
+				continue
+			}
+			found = true
+			fillEmpty(&merged[i].DeprecatedIn, version.DeprecatedIn)
+			fillEmpty(&merged[i].RemovedIn, version.RemovedIn)
+			fillEmpty(&merged[i].ReplacementAPI, version.ReplacementAPI)
+			fillEmpty(&merged[i].ReplacementAvailableIn, version.ReplacementAvailableIn)
+			fillEmpty(&merged[i].Component, version.Component)
+			break
+		}
+		if !found {
+			merged = append(merged, version)
+		}
+	}
+	return merged
+}
+
+func fillEmpty(value *string, fallback string) {
+	if *value == "" {
+		*value = fallback
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// swaggerV2Ingress is a trimmed down /openapi/v2 document serving
+// extensions/v1beta1 Ingress and networking.k8s.io/v1beta1 Ingress
+var swaggerV2Ingress = `{
+  "swagger": "2.0",
+  "definitions": {
+    "io.k8s.api.extensions.v1beta1.Ingress": {
+      "description": "Ingress is a collection of rules. DEPRECATED - This group version of Ingress is deprecated by networking.k8s.io/v1beta1 Ingress.",
+      "x-kubernetes-group-version-kind": [{"group": "extensions", "kind": "Ingress", "version": "v1beta1"}]
+    },
+    "io.k8s.api.networking.v1beta1.Ingress": {
+      "description": "Ingress is a collection of rules.",
+      "x-kubernetes-group-version-kind": [{"group": "networking.k8s.io", "kind": "Ingress", "version": "v1beta1"}]
+    },
+    "io.k8s.apimachinery.pkg.apis.meta.v1.DeleteOptions": {
+      "description": "DeleteOptions may be provided when deleting an API object. Deprecated fields are ignored.",
+      "x-kubernetes-group-version-kind": [
+        {"group": "extensions", "kind": "DeleteOptions", "version": "v1beta1"},
+        {"group": "networking.k8s.io", "kind": "DeleteOptions", "version": "v1beta1"}
+      ]
+    }
+  },
+  "paths": {
+    "/apis/extensions/v1beta1/ingresses": {
+      "get": {"x-kubernetes-action": "list", "x-kubernetes-group-version-kind": {"group": "extensions", "kind": "Ingress", "version": "v1beta1"}}
+    },
+    "/apis/networking.k8s.io/v1beta1/ingresses": {
+      "get": {"x-kubernetes-action": "list", "x-kubernetes-group-version-kind": {"group": "networking.k8s.io", "kind": "Ingress", "version": "v1beta1"}},
+      "parameters": [{"name": "pretty"}]
+    },
+    "/apis/networking.k8s.io/v1beta1/namespaces/{namespace}/ingresses/{name}": {
+      "delete": {"x-kubernetes-action": "delete", "x-kubernetes-group-version-kind": {"group": "networking.k8s.io", "kind": "Ingress", "version": "v1beta1"}}
+    }
+  }
+}`
+
+// openAPIV3NetworkingV1 is a trimmed down /openapi/v3/apis/networking.k8s.io/v1 document
+var openAPIV3NetworkingV1 = `{
+  "openapi": "3.0.0",
+  "components": {
+    "schemas": {
+      "io.k8s.api.networking.v1.Ingress": {
+        "description": "Ingress is a collection of rules.",
+        "x-kubernetes-group-version-kind": [{"group": "networking.k8s.io", "kind": "Ingress", "version": "v1"}]
+      }
+    }
+  },
+  "paths": {
+    "/apis/networking.k8s.io/v1/ingresses": {
+      "get": {"x-kubernetes-action": "list", "x-kubernetes-group-version-kind": {"group": "networking.k8s.io", "kind": "Ingress", "version": "v1"}}
+    }
+  }
+}`
+
+// openAPIV3NetworkingV1beta1 marks networking.k8s.io/v1beta1 Ingress deprecated
+var openAPIV3NetworkingV1beta1 = `{
+  "openapi": "3.0.0",
+  "components": {
+    "schemas": {
+      "io.k8s.api.networking.v1beta1.Ingress": {
+        "description": "Ingress is a collection of rules.",
+        "deprecated": true,
+        "x-kubernetes-group-version-kind": [{"group": "networking.k8s.io", "kind": "Ingress", "version": "v1beta1"}]
+      }
+    }
+  },
+  "paths": {
+    "/apis/networking.k8s.io/v1beta1/ingresses": {
+      "get": {"x-kubernetes-action": "list", "x-kubernetes-group-version-kind": {"group": "networking.k8s.io", "kind": "Ingress", "version": "v1beta1"}}
+    }
+  }
+}`
+
+func TestGenerateVersions(t *testing.T) {
+	releases := []ReleaseSpec{
+		// out of order on purpose
+		{Version: "v1.22.0", Documents: [][]byte{[]byte(openAPIV3NetworkingV1)}},
+		{Version: "v1.14.0", Documents: [][]byte{[]byte(swaggerV2Ingress)}},
+		{Version: "v1.19.0", Documents: [][]byte{[]byte(openAPIV3NetworkingV1), []byte(openAPIV3NetworkingV1beta1)}},
+	}
+	got, err := GenerateVersions(releases, "k8s")
+	assert.NoError(t, err)
+	assert.Equal(t, []Version{
+		{
+			Name:         "extensions/v1beta1",
+			Kind:         "Ingress",
+			DeprecatedIn: "v1.14.0",
+			RemovedIn:    "v1.19.0",
+			// named in the deprecation notice
+			ReplacementAPI:         "networking.k8s.io/v1beta1",
+			ReplacementAvailableIn: "v1.14.0",
+			Component:              "k8s",
+		},
+		{
+			Name:                   "networking.k8s.io/v1beta1",
+			Kind:                   "Ingress",
+			DeprecatedIn:           "v1.19.0",
+			RemovedIn:              "v1.22.0",
+			ReplacementAPI:         "networking.k8s.io/v1",
+			ReplacementAvailableIn: "v1.19.0",
+			Component:              "k8s",
+		},
+	}, got)
+
+	_, err = GenerateVersions([]ReleaseSpec{{Version: "1.22", Documents: [][]byte{[]byte(openAPIV3NetworkingV1)}}}, "k8s")
+	assert.Error(t, err)
+	_, err = GenerateVersions([]ReleaseSpec{{Version: "v1.22.0", Documents: [][]byte{[]byte("not json")}}}, "k8s")
+	assert.Error(t, err)
+}
+
+func Test_parseReleaseSpec_kubernetesDeprecated(t *testing.T) {
+	data := `{"definitions": {
+	  "io.k8s.api.batch.v1beta1.CronJob": {
+	    "x-kubernetes-deprecated": true,
+	    "x-kubernetes-group-version-kind": [{"group": "batch", "kind": "CronJob", "version": "v1beta1"}]
+	  },
+	  "io.k8s.api.batch.v1.CronJob": {
+	    "x-kubernetes-deprecated": false,
+	    "x-kubernetes-group-version-kind": [{"group": "batch", "kind": "CronJob", "version": "v1"}]
+	  }
+	}}`
+	got, err := parseReleaseSpec(ReleaseSpec{Version: "v1.21.0", Documents: [][]byte{[]byte(data)}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[groupVersionKind]servedKind{
+		{Group: "batch", Version: "v1beta1", Kind: "CronJob"}: {deprecated: true},
+		{Group: "batch", Version: "v1", Kind: "CronJob"}:      {},
+	}, got)
+}
+
+func TestGeneratedTargetVersions(t *testing.T) {
+	releases := []ReleaseSpec{{Version: "v1.22.0"}, {Version: "v1.29.0"}, {Version: "v1.19.0"}}
+	assert.Equal(t, map[string]string{"istio": "v1.29.0"}, GeneratedTargetVersions(releases, "istio"))
+	assert.Nil(t, GeneratedTargetVersions(nil, "istio"))
+}
+
+func Test_parseReleaseSpec_withoutPaths(t *testing.T) {
+	data := `{"definitions": {
+	  "io.k8s.api.policy.v1beta1.PodSecurityPolicy": {
+	    "description": "PodSecurityPolicy governs the ability to make requests. Deprecated in 1.21.",
+	    "x-kubernetes-group-version-kind": [{"group": "policy", "kind": "PodSecurityPolicy", "version": "v1beta1"}]
+	  },
+	  "io.k8s.api.policy.v1beta1.PodSecurityPolicyList": {
+	    "x-kubernetes-group-version-kind": [{"group": "policy", "kind": "PodSecurityPolicyList", "version": "v1beta1"}]
+	  }
+	}}`
+	got, err := parseReleaseSpec(ReleaseSpec{Version: "v1.21.0", Documents: [][]byte{[]byte(data)}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[groupVersionKind]servedKind{
+		{Group: "policy", Version: "v1beta1", Kind: "PodSecurityPolicy"}: {deprecated: true},
+	}, got)
+}
+
+func Test_compareKubeVersions(t *testing.T) {
+	assert.Greater(t, compareKubeVersions("v1", "v1beta1"), 0)
+	assert.Greater(t, compareKubeVersions("v2", "v1"), 0)
+	assert.Greater(t, compareKubeVersions("v1beta2", "v1beta1"), 0)
+	assert.Greater(t, compareKubeVersions("v1beta1", "v2alpha1"), 0)
+	assert.Equal(t, 0, compareKubeVersions("v1", "v1"))
+}
+
+func TestMergeVersions(t *testing.T) {
+	existing := []Version{
+		{Name: "extensions/v1beta1", Kind: "Ingress", DeprecatedIn: "v1.14.0", RemovedIn: "v1.22.0", ReplacementAPI: "networking.k8s.io/v1", Component: "k8s"},
+	}
+	generated := []Version{
+		{Name: "extensions/v1beta1", Kind: "Ingress", DeprecatedIn: "v1.15.0", RemovedIn: "v1.22.0", ReplacementAPI: "networking.k8s.io/v1beta1", ReplacementAvailableIn: "v1.14.0", Component: "k8s"},
+		{Name: "policy/v1beta1", Kind: "PodSecurityPolicy", DeprecatedIn: "v1.21.0", RemovedIn: "v1.25.0", Component: "k8s"},
+	}
+	got := MergeVersions(existing, generated)
+	assert.Equal(t, []Version{
+		{Name: "extensions/v1beta1", Kind: "Ingress", DeprecatedIn: "v1.14.0", RemovedIn: "v1.22.0", ReplacementAPI: "networking.k8s.io/v1", ReplacementAvailableIn: "v1.14.0", Component: "k8s"},
+		{Name: "policy/v1beta1", Kind: "PodSecurityPolicy", DeprecatedIn: "v1.21.0", RemovedIn: "v1.25.0", Component: "k8s"},
+	}, got)
+	// the existing list is not modified
+	assert.Equal(t, "", existing[0].ReplacementAvailableIn)
+}
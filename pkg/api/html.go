@@ -0,0 +1,204 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+	"sort"
+)
+
+// reportTemplate is the template of -o html. It is a single file with inline styles and
+// scripts, so that the report works offline.
+//
+//go:embed templates/report.html
+var reportTemplate string
+
+// htmlReport is the data of reportTemplate
+type htmlReport struct {
+	TargetVersions []htmlCount
+	Total          int
+	Removed        int
+	Deprecated     int
+	Suppressed     int
+	ByComponent    []htmlCount
+	ByNamespace    []htmlCount
+	ByRemovedIn    []htmlCount
+	Headers        []string
+	Rows           []htmlRow
+	Remediations   []htmlRemediation
+}
+
+// htmlCount is a row of a summary table
+type htmlCount struct {
+	Name       string
+	Value      string
+	Total      int
+	Removed    int
+	Deprecated int
+}
+
+// htmlSummary is a summary table with its title
+type htmlSummary struct {
+	Title  string
+	Counts []htmlCount
+}
+
+// Summaries returns the summary tables of the report
+func (report htmlReport) Summaries() []htmlSummary {
+	return []htmlSummary{
+		{Title: "Component", Counts: report.ByComponent},
+		{Title: "Namespace", Counts: report.ByNamespace},
+		{Title: "Removed in", Counts: report.ByRemovedIn},
+	}
+}
+
+// htmlRow is a row of the findings table, with the state of the finding for styling
+type htmlRow struct {
+	State string
+	Cells []string
+}
+
+// htmlRemediation is the remediation note of a rule and the number of findings of it
+type htmlRemediation struct {
+	ID          string
+	Description string
+	Help        string
+	Count       int
+}
+
+// htmlState returns the state of an output used to style its row
+func htmlState(output *Output) string {
+	switch {
+	case output.Suppressed:
+		return "suppressed"
+	case output.Rule != "":
+		return "rule"
+	case output.Removed:
+		return "removed"
+	}
+	return "deprecated"
+}
+
+// countBy returns a summary row for every value of key in outputs, sorted by value
+func countBy(outputs []*Output, key func(output *Output) string) []htmlCount {
+	counts := map[string]*htmlCount{}
+	for _, output := range outputs {
+		name := key(output)
+		count, found := counts[name]
+		if !found {
+			count = &htmlCount{Name: name}
+			counts[name] = count
+		}
+		count.Total++
+		switch {
+		case output.Removed:
+			count.Removed++
+		case output.Deprecated:
+			count.Deprecated++
+		}
+	}
+	var rows []htmlCount
+	for _, count := range counts {
+		rows = append(rows, *count)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].Name < rows[j].Name
+	})
+	return rows
+}
+
+// html returns the data of the HTML report of the outputs, with the wide columns or the
+// custom columns if there are any
+func (instance *Instance) html() htmlReport {
+	report := htmlReport{}
+
+	var components []string
+	for component := range instance.TargetVersions {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	for _, component := range components {
+		report.TargetVersions = append(report.TargetVersions, htmlCount{Name: component, Value: instance.TargetVersions[component]})
+	}
+
+	var outputs []*Output
+	for _, output := range instance.Outputs {
+		if output.APIVersion == nil {
+			continue
+		}
+		outputs = append(outputs, output)
+		switch {
+		case output.Suppressed:
+			report.Suppressed++
+		case output.Removed:
+			report.Removed++
+		case output.Deprecated:
+			report.Deprecated++
+		}
+	}
+	report.Total = len(outputs)
+	report.ByComponent = countBy(outputs, func(output *Output) string { return output.APIVersion.Component })
+	report.ByNamespace = countBy(outputs, func(output *Output) string { return namespace{}.value(output) })
+	report.ByRemovedIn = countBy(outputs, func(output *Output) string { return orNotApplicable(output.APIVersion.RemovedIn) })
+
+	var columns columnList
+	if len(instance.CustomColumns) >= 1 {
+		columns = instance.customColumns()
+	} else {
+		columns = instance.wideColumns()
+	}
+	columnIndexes := make([]int, 0, len(columns))
+	for k := range columns {
+		columnIndexes = append(columnIndexes, k)
+	}
+	sort.Ints(columnIndexes)
+	for _, k := range columnIndexes {
+		report.Headers = append(report.Headers, columns[k].header())
+	}
+	remediations := map[string]*htmlRemediation{}
+	for _, output := range outputs {
+		row := htmlRow{State: htmlState(output)}
+		for _, k := range columnIndexes {
+			row.Cells = append(row.Cells, columns[k].value(output))
+		}
+		report.Rows = append(report.Rows, row)
+
+		rule := newSarifRule(output)
+		remediation, found := remediations[rule.ID]
+		if !found {
+			remediation = &htmlRemediation{ID: rule.ID, Description: rule.ShortDescription.Text, Help: rule.Help.Text}
+			remediations[rule.ID] = remediation
+		}
+		remediation.Count++
+	}
+	for _, remediation := range remediations {
+		report.Remediations = append(report.Remediations, *remediation)
+	}
+	sort.Slice(report.Remediations, func(i, j int) bool {
+		return report.Remediations[i].ID < report.Remediations[j].ID
+	})
+	return report
+}
+
+// writeHTML writes the HTML report of the outputs to w
+func (instance *Instance) writeHTML(w io.Writer) error {
+	t, err := template.New("report").Parse(reportTemplate)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, instance.html())
+}
@@ -0,0 +1,127 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstance_html(t *testing.T) {
+	deployment := &Version{Name: "extensions/v1beta1", Kind: "Deployment", DeprecatedIn: "v1.9.0", RemovedIn: "v1.16.0", ReplacementAPI: "apps/v1", ReplacementAvailableIn: "v1.9.0", Component: "k8s"}
+	psp := &Version{Name: "policy/v1beta1", Kind: "PodSecurityPolicy", DeprecatedIn: "v1.21.0", RemovedIn: "v1.25.0", Component: "k8s"}
+	certificate := &Version{Name: "cert-manager.io/v1alpha2", Kind: "Certificate", DeprecatedIn: "v1.4.0", Component: "cert-manager"}
+
+	instance := &Instance{
+		Outputs: []*Output{
+			{Name: "web", Namespace: "apps", APIVersion: deployment, Deprecated: true, Removed: true},
+			{Name: "worker", Namespace: "apps", APIVersion: deployment, Deprecated: true, Removed: true, Suppressed: true},
+			{Name: "restricted", APIVersion: psp, Deprecated: true},
+			{Name: "tls", Namespace: "apps", APIVersion: certificate, Deprecated: true},
+		},
+		TargetVersions: map[string]string{"k8s": "v1.22.0", "cert-manager": "v1.5.0"},
+		CustomColumns:  []string{"NAME", "NAMESPACE", "VERSION"},
+	}
+	report := instance.html()
+
+	assert.Equal(t, []htmlCount{{Name: "cert-manager", Value: "v1.5.0"}, {Name: "k8s", Value: "v1.22.0"}}, report.TargetVersions)
+	assert.Equal(t, 4, report.Total)
+	assert.Equal(t, 1, report.Removed)
+	assert.Equal(t, 2, report.Deprecated)
+	assert.Equal(t, 1, report.Suppressed)
+	assert.Equal(t, []htmlCount{
+		{Name: "cert-manager", Total: 1, Deprecated: 1},
+		{Name: "k8s", Total: 3, Removed: 2, Deprecated: 1},
+	}, report.ByComponent)
+	assert.Equal(t, []htmlCount{
+		{Name: "<UNKNOWN>", Total: 1, Deprecated: 1},
+		{Name: "apps", Total: 3, Removed: 2, Deprecated: 1},
+	}, report.ByNamespace)
+	assert.Equal(t, []htmlCount{
+		{Name: "n/a", Total: 1, Deprecated: 1},
+		{Name: "v1.16.0", Total: 2, Removed: 2},
+		{Name: "v1.25.0", Total: 1, Deprecated: 1},
+	}, report.ByRemovedIn)
+
+	assert.Equal(t, []string{"NAME", "NAMESPACE", "VERSION"}, report.Headers)
+	assert.Equal(t, []htmlRow{
+		{State: "removed", Cells: []string{"web", "apps", "extensions/v1beta1"}},
+		{State: "suppressed", Cells: []string{"worker", "apps", "extensions/v1beta1"}},
+		{State: "deprecated", Cells: []string{"restricted", "<UNKNOWN>", "policy/v1beta1"}},
+		{State: "deprecated", Cells: []string{"tls", "apps", "cert-manager.io/v1alpha2"}},
+	}, report.Rows)
+
+	assert.Equal(t, []htmlRemediation{
+		{
+			ID:          "cert-manager/Certificate/cert-manager.io/v1alpha2",
+			Description: "Certificate cert-manager.io/v1alpha2 is deprecated",
+			Help:        "Certificate cert-manager.io/v1alpha2 is deprecated in v1.4.0. There is no replacement.",
+			Count:       1,
+		},
+		{
+			ID:          "k8s/Deployment/extensions/v1beta1",
+			Description: "Deployment extensions/v1beta1 is deprecated",
+			Help:        "Deployment extensions/v1beta1 is deprecated in v1.9.0 and removed in v1.16.0. Replace it with apps/v1, available in v1.9.0.",
+			Count:       2,
+		},
+		{
+			ID:          "k8s/PodSecurityPolicy/policy/v1beta1",
+			Description: "PodSecurityPolicy policy/v1beta1 is deprecated",
+			Help:        "PodSecurityPolicy policy/v1beta1 is deprecated in v1.21.0 and removed in v1.25.0. There is no replacement.",
+			Count:       1,
+		},
+	}, report.Remediations)
+}
+
+func TestInstance_writeHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		outputs  []*Output
+		contains []string
+	}{
+		{
+			name:     "no findings",
+			contains: []string{"There were no resources found with known deprecated apiVersions."},
+		},
+		{
+			name: "escaped finding",
+			outputs: []*Output{
+				{Name: "<script>alert(1)</script>", APIVersion: &Version{Name: "extensions/v1beta1", Kind: "Ingress", Component: "platform"}, Rule: "ingress-class", Message: "use spec.ingressClassName"},
+			},
+			contains: []string{
+				`<tr class="rule">`,
+				"&lt;script&gt;alert(1)&lt;/script&gt;",
+				"<th>RULE</th>",
+				"<dd>use spec.ingressClassName</dd>",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := &Instance{Outputs: tt.outputs}
+			var buf bytes.Buffer
+			assert.NoError(t, instance.writeHTML(&buf))
+			got := buf.String()
+			for _, want := range tt.contains {
+				assert.Contains(t, got, want)
+			}
+			assert.NotContains(t, got, "<script>alert(1)</script>")
+			assert.NotContains(t, got, "src=")
+			assert.NotContains(t, got, "<link")
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// IgnoreAnnotation is the annotation that suppresses the findings of an object, such as
+	// lamb.io/ignore: removals,until=2027-01-01
+	IgnoreAnnotation = "lamb.io/ignore"
+	// IgnoreComment is the comment on the apiVersion line that suppresses all findings of an object
+	IgnoreComment = "lamb:ignore"
+)
+
+// ignoreRule is what the ignore annotation or comment of an object suppresses
+type ignoreRule struct {
+	deprecations bool
+	removals     bool
+	// until is the date after which the rule no longer applies, if set
+	until time.Time
+	// source describes where the rule came from
+	source string
+}
+
+// parseIgnoreAnnotation parses the value of IgnoreAnnotation, one of deprecations, removals
+// or all, optionally followed by until= and a date, separated by commas or spaces
+func parseIgnoreAnnotation(value string) (*ignoreRule, error) {
+	rule := &ignoreRule{source: fmt.Sprintf("annotation %s=%s", IgnoreAnnotation, value)}
+	parts := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == ';'
+	})
+	for _, part := range parts {
+		switch {
+		case part == "deprecations":
+			rule.deprecations = true
+		case part == "removals":
+			rule.removals = true
+		case part == "all":
+			rule.deprecations = true
+			rule.removals = true
+		case strings.HasPrefix(part, "until="):
+			until, err := time.Parse(BaselineDateFormat, strings.TrimPrefix(part, "until="))
+			if err != nil {
+				return nil, fmt.Errorf("invalid until date %q, must be like %s", strings.TrimPrefix(part, "until="), BaselineDateFormat)
+			}
+			rule.until = until
+		default:
+			return nil, fmt.Errorf("unknown value %q, must be deprecations, removals or all, optionally with until=%s", part, BaselineDateFormat)
+		}
+	}
+	if !rule.deprecations && !rule.removals {
+		return nil, fmt.Errorf("must be deprecations, removals or all")
+	}
+	return rule, nil
+}
+
+// ignoreRuleOf returns the ignore rule of a stub, from its ignore comment or annotation,
+// or nil if it has neither. An invalid annotation is logged and ignored.
+func ignoreRuleOf(stub *Stub) *ignoreRule {
+	if stub.IgnoreComment {
+		return &ignoreRule{deprecations: true, removals: true, source: "comment # " + IgnoreComment}
+	}
+	value, found := stub.Metadata.Annotations[IgnoreAnnotation]
+	if !found {
+		return nil
+	}
+	rule, err := parseIgnoreAnnotation(value)
+	if err != nil {
+		klog.Warningf("ignoring invalid %s annotation on %s %s: %s", IgnoreAnnotation, stub.Kind, stub.Metadata.Name, err.Error())
+		return nil
+	}
+	return rule
+}
+
+// suppresses returns whether the rule suppresses output at now
+func (rule *ignoreRule) suppresses(output *Output, now time.Time) bool {
+	if rule == nil {
+		return false
+	}
+	if !rule.until.IsZero() && !now.Before(rule.until.AddDate(0, 0, 1)) {
+		klog.V(2).Infof("%s on %s expired on %s", rule.source, output.Name, rule.until.Format(BaselineDateFormat))
+		return false
+	}
+	if output.Rule != "" {
+		return rule.deprecations && rule.removals
+	}
+	if output.Removed {
+		return rule.removals
+	}
+	return output.Deprecated && rule.deprecations
+}
+
+// hasIgnoreComment returns whether the apiVersion line of a manifest has an ignore comment
+func hasIgnoreComment(key *yaml.Node, value *yaml.Node) bool {
+	for _, node := range []*yaml.Node{key, value} {
+		if node != nil && strings.HasPrefix(strings.TrimSpace(strings.TrimPrefix(node.LineComment, "#")), IgnoreComment) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyIgnoreRules marks the outputs suppressed by the ignore annotation or comment of their
+// object. They are removed from the outputs unless ShowSuppressed is set.
+func (instance *Instance) applyIgnoreRules(now time.Time) {
+	var outputs []*Output
+	for _, output := range instance.Outputs {
+		if output.ignore.suppresses(output, now) {
+			output.Suppressed = true
+			output.SuppressedBy = output.ignore.source
+			if !instance.ShowSuppressed {
+				instance.hiddenSuppressed++
+				continue
+			}
+		}
+		outputs = append(outputs, output)
+	}
+	instance.Outputs = outputs
+}
+
+// SuppressionSummary returns a line for the footer with the number of suppressed outputs,
+// or an empty string if there are none
+func (instance *Instance) SuppressionSummary() string {
+	suppressed := instance.hiddenSuppressed
+	for _, output := range instance.Outputs {
+		if output.Suppressed {
+			suppressed++
+		}
+	}
+	if suppressed == 0 {
+		return ""
+	}
+	if instance.hiddenSuppressed > 0 {
+		return fmt.Sprintf("%d suppressed (%d hidden, use --show-suppressed to show them)", suppressed, instance.hiddenSuppressed)
+	}
+	return fmt.Sprintf("%d suppressed", suppressed)
+}
@@ -0,0 +1,156 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseIgnoreAnnotation(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    *ignoreRule
+		wantErr string
+	}{
+		{
+			value: "deprecations",
+			want:  &ignoreRule{deprecations: true, source: "annotation lamb.io/ignore=deprecations"},
+		},
+		{
+			value: "removals",
+			want:  &ignoreRule{removals: true, source: "annotation lamb.io/ignore=removals"},
+		},
+		{
+			value: "all,until=2027-01-01",
+			want: &ignoreRule{
+				deprecations: true,
+				removals:     true,
+				until:        time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+				source:       "annotation lamb.io/ignore=all,until=2027-01-01",
+			},
+		},
+		{
+			value: "removals until=2027-01-01",
+			want: &ignoreRule{
+				removals: true,
+				until:    time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+				source:   "annotation lamb.io/ignore=removals until=2027-01-01",
+			},
+		},
+		{
+			value:   "everything",
+			wantErr: `unknown value "everything", must be deprecations, removals or all, optionally with until=2006-01-02`,
+		},
+		{
+			value:   "all,until=next-year",
+			wantErr: `invalid until date "next-year", must be like 2006-01-02`,
+		},
+		{
+			value:   "until=2027-01-01",
+			wantErr: "must be deprecations, removals or all",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseIgnoreAnnotation(tt.value)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestInstance_IsVersioned_ignore(t *testing.T) {
+	instance := &Instance{
+		DeprecatedVersions: []Version{
+			{Name: "extensions/v1beta1", Kind: "Deployment", DeprecatedIn: "v1.9.0", RemovedIn: "v1.16.0", Component: "k8s"},
+		},
+	}
+	data := []byte(`apiVersion: extensions/v1beta1 # lamb:ignore until the chart is upgraded
+kind: Deployment
+metadata:
+  name: commented
+---
+apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: annotated
+  annotations:
+    lamb.io/ignore: removals,until=2027-01-01
+---
+apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: invalid
+  annotations:
+    lamb.io/ignore: sometimes
+---
+apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: plain
+`)
+	outputs, err := instance.IsVersioned(data)
+	assert.NoError(t, err)
+	assert.Len(t, outputs, 4)
+	assert.Equal(t, &ignoreRule{deprecations: true, removals: true, source: "comment # lamb:ignore"}, outputs[0].ignore)
+	assert.Equal(t, "annotation lamb.io/ignore=removals,until=2027-01-01", outputs[1].ignore.source)
+	assert.Nil(t, outputs[2].ignore)
+	assert.Nil(t, outputs[3].ignore)
+}
+
+func TestInstance_applyIgnoreRules(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	expired := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	newOutputs := func() []*Output {
+		return []*Output{
+			{Name: "removed-all", Removed: true, Deprecated: true, ignore: &ignoreRule{deprecations: true, removals: true, source: "comment # lamb:ignore"}},
+			{Name: "removed-deprecations", Removed: true, Deprecated: true, ignore: &ignoreRule{deprecations: true}},
+			{Name: "deprecated-deprecations", Deprecated: true, ignore: &ignoreRule{deprecations: true}},
+			{Name: "deprecated-removals", Deprecated: true, ignore: &ignoreRule{removals: true}},
+			{Name: "removed-expired", Removed: true, ignore: &ignoreRule{removals: true, until: expired}},
+			{Name: "plain", Removed: true},
+		}
+	}
+	names := func(outputs []*Output) []string {
+		var names []string
+		for _, output := range outputs {
+			names = append(names, output.Name)
+		}
+		return names
+	}
+
+	instance := &Instance{Outputs: newOutputs()}
+	instance.applyIgnoreRules(now)
+	assert.Equal(t, []string{"removed-deprecations", "deprecated-removals", "removed-expired", "plain"}, names(instance.Outputs))
+	assert.Equal(t, "2 suppressed (2 hidden, use --show-suppressed to show them)", instance.SuppressionSummary())
+
+	instance = &Instance{Outputs: newOutputs(), ShowSuppressed: true}
+	instance.applyIgnoreRules(now)
+	assert.Len(t, instance.Outputs, 6)
+	assert.True(t, instance.Outputs[0].Suppressed)
+	assert.Equal(t, "comment # lamb:ignore", instance.Outputs[0].SuppressedBy)
+	assert.True(t, instance.Outputs[2].Suppressed)
+	assert.False(t, instance.Outputs[4].Suppressed)
+	assert.Equal(t, "2 suppressed", instance.SuppressionSummary())
+
+	assert.Equal(t, "", (&Instance{Outputs: []*Output{{Name: "plain"}}}).SuppressionSummary())
+}
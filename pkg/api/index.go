@@ -0,0 +1,145 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"sort"
+	"sync"
+)
+
+// versionIndex looks up the deprecated version for an apiVersion and kind without
+// scanning every version. It holds its own copy of the versions and is never modified
+// after it is built, apart from the cache of wildcard lookups.
+type versionIndex struct {
+	// exact holds versions with an exact version and kind, keyed by indexKey
+	exact map[string]*Version
+	// kindPatterns holds versions with an exact version and a glob or regex kind,
+	// keyed by version and sorted from the most to the least specific kind
+	kindPatterns map[string][]*Version
+	// anyKind is the kind-agnostic fallback, versions with an exact version and an empty kind
+	anyKind map[string]*Version
+	// wildcards holds versions with a glob or regex version, sorted from the most to the least specific
+	wildcards []*Version
+	// wildcardMatches caches lookups against wildcards, keyed by indexKey
+	wildcardMatches sync.Map
+}
+
+func indexKey(apiVersion string, kind string) string {
+	return apiVersion + "|" + kind
+}
+
+// newVersionIndex builds an index of versions. When two versions are equally specific
+// the one that comes first in versions wins.
+func newVersionIndex(versions []Version) *versionIndex {
+	index := &versionIndex{
+		exact:        make(map[string]*Version, len(versions)),
+		kindPatterns: map[string][]*Version{},
+		anyKind:      map[string]*Version{},
+	}
+	copied := make([]Version, len(versions))
+	copy(copied, versions)
+	for i := range copied {
+		version := &copied[i]
+		if patternTypeOf(version.Name) != PatternExact {
+			index.wildcards = append(index.wildcards, version)
+			continue
+		}
+		switch patternTypeOf(version.Kind) {
+		case PatternExact:
+			key := indexKey(version.Name, version.Kind)
+			if _, found := index.exact[key]; !found {
+				index.exact[key] = version
+			}
+		case PatternAny:
+			if _, found := index.anyKind[version.Name]; !found {
+				index.anyKind[version.Name] = version
+			}
+		default:
+			index.kindPatterns[version.Name] = append(index.kindPatterns[version.Name], version)
+		}
+	}
+	for _, versions := range index.kindPatterns {
+		sort.SliceStable(versions, func(i, j int) bool {
+			return comparePatterns(versions[i].Kind, versions[j].Kind) > 0
+		})
+	}
+	sort.SliceStable(index.wildcards, func(i, j int) bool {
+		return index.wildcards[i].isMoreSpecific(index.wildcards[j])
+	})
+	return index
+}
+
+// lookup returns the most specific version matching apiVersion and kind, or nil.
+// Any version with an exact version outranks every version with a wildcard version,
+// so the buckets are checked in order of precedence.
+func (index *versionIndex) lookup(apiVersion string, kind string) *Version {
+	key := indexKey(apiVersion, kind)
+	if version, found := index.exact[key]; found {
+		return version
+	}
+	for _, version := range index.kindPatterns[apiVersion] {
+		if matchPattern(version.Kind, kind) {
+			return version
+		}
+	}
+	if version, found := index.anyKind[apiVersion]; found {
+		return version
+	}
+	if len(index.wildcards) == 0 {
+		return nil
+	}
+	if cached, found := index.wildcardMatches.Load(key); found {
+		return cached.(*Version)
+	}
+	var match *Version
+	for _, version := range index.wildcards {
+		if matchPattern(version.Kind, kind) && matchPattern(version.Name, apiVersion) {
+			match = version
+			break
+		}
+	}
+	index.wildcardMatches.Store(key, match)
+	return match
+}
+
+// versionOf returns a copy of the version matching the stub, with the apiVersion
+// and kind of the stub rather than the patterns that matched them
+func (index *versionIndex) versionOf(stub *Stub) *Version {
+	match := index.lookup(stub.APIVersion, stub.Kind)
+	if match == nil {
+		return nil
+	}
+	found := *match
+	found.Name = stub.APIVersion
+	found.Kind = stub.Kind
+	return &found
+}
+
+// BuildIndex indexes DeprecatedVersions so that looking up the version of an object does
+// not scan every deprecated version. It should be called once the Instance is constructed.
+// Changes to DeprecatedVersions after that are not seen until BuildIndex is called again.
+func (instance *Instance) BuildIndex() {
+	instance.index = newVersionIndex(instance.DeprecatedVersions)
+}
+
+// versionIndex returns the index built by BuildIndex. If BuildIndex was never called, the
+// index of the current DeprecatedVersions is built on first use and kept. The index is held
+// by pointer, so copies of the Instance made after that share it.
+func (instance *Instance) versionIndex() *versionIndex {
+	if instance.index == nil {
+		instance.index = newVersionIndex(instance.DeprecatedVersions)
+	}
+	return instance.index
+}
@@ -0,0 +1,223 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Line      int           `xml:"line,attr,omitempty"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// objectIdentity is what makes two outputs findings of the same object
+func objectIdentity(output *Output) string {
+	return strings.Join([]string{
+		output.FilePath,
+		output.Namespace,
+		output.Name,
+		output.APIVersion.Kind,
+		output.APIVersion.Name,
+	}, "\x00")
+}
+
+// objectsOf returns the first output of every object of outputs, in order
+func objectsOf(outputs []*Output) []*Output {
+	var objects []*Output
+	seen := map[string]bool{}
+	for _, output := range outputs {
+		if output.APIVersion == nil {
+			continue
+		}
+		identity := objectIdentity(output)
+		if seen[identity] {
+			continue
+		}
+		seen[identity] = true
+		objects = append(objects, output)
+	}
+	return objects
+}
+
+// junitSuiteName groups the objects of a file, or of a namespace for Helm releases and
+// the cluster
+func junitSuiteName(output *Output) string {
+	switch {
+	case output.FilePath != "":
+		return output.FilePath
+	case output.Namespace != "":
+		return output.Namespace
+	}
+	return "lamb"
+}
+
+// junitFindingType is the type of the failure of a finding
+func junitFindingType(output *Output) string {
+	switch {
+	case output.Rule != "":
+		return "rule"
+	case output.Removed:
+		return "removed"
+	}
+	return "deprecated"
+}
+
+// junitTestCaseOf returns the test case of an object with findings. Removed objects and
+// findings with a non-zero exit code in the policy fail, and any other finding is skipped,
+// as are suppressed and unchanged findings. Fixed findings pass.
+func (instance *Instance) junitTestCaseOf(object *Output, findings []*Output) junitTestCase {
+	testCase := junitTestCase{
+		Name:      fmt.Sprintf("%s %s (%s)", object.APIVersion.Kind, object.Name, object.APIVersion.Name),
+		ClassName: junitSuiteName(object),
+		File:      object.FilePath,
+		Line:      object.Line,
+	}
+	policy := instance.policy()
+	var failures, skipped []*Output
+	var skippedReasons []string
+	for _, output := range findings {
+		switch {
+		case output.Change == ChangeFixed:
+			continue
+		case output.Suppressed:
+			skipped = append(skipped, output)
+			skippedReasons = append(skippedReasons, fmt.Sprintf("%s Suppressed by %s.", findingMessage(output), output.SuppressedBy))
+			continue
+		case output.Change == ChangeUnchanged:
+			skipped = append(skipped, output)
+			skippedReasons = append(skippedReasons, findingMessage(output)+" Unchanged since the previous report.")
+			continue
+		}
+		_, exitCode, _ := policy.outcome(output, instance.TargetVersions)
+		if (output.Removed && output.Rule == "") || exitCode > 0 {
+			failures = append(failures, output)
+		} else {
+			skipped = append(skipped, output)
+			skippedReasons = append(skippedReasons, findingMessage(output))
+		}
+	}
+	if len(failures) > 0 {
+		var messages []string
+		for _, output := range failures {
+			messages = append(messages, findingMessage(output))
+		}
+		testCase.Failure = &junitFailure{
+			Message: messages[0],
+			Type:    junitFindingType(failures[0]),
+			Text:    strings.Join(messages, "\n"),
+		}
+	} else if len(skipped) > 0 {
+		testCase.Skipped = &junitSkipped{Message: strings.Join(skippedReasons, " ")}
+	}
+	return testCase
+}
+
+// junit returns a test suite for every file or namespace with a test case for every object.
+// Objects without findings pass, see TrackObjects.
+func (instance *Instance) junit() junitTestSuites {
+	objects := instance.Objects
+	if objects == nil {
+		objects = objectsOf(instance.Outputs)
+	}
+	findings := map[string][]*Output{}
+	for _, output := range instance.Outputs {
+		if output.APIVersion == nil {
+			continue
+		}
+		identity := objectIdentity(output)
+		findings[identity] = append(findings[identity], output)
+	}
+	// fixed findings of objects that are no longer found still get a passing test case
+	known := map[string]bool{}
+	for _, object := range objects {
+		known[objectIdentity(object)] = true
+	}
+	for _, object := range objectsOf(instance.Outputs) {
+		if !known[objectIdentity(object)] {
+			objects = append(objects, object)
+		}
+	}
+
+	suites := map[string]*junitTestSuite{}
+	for _, object := range objects {
+		name := junitSuiteName(object)
+		suite, found := suites[name]
+		if !found {
+			suite = &junitTestSuite{Name: name}
+			suites[name] = suite
+		}
+		testCase := instance.junitTestCaseOf(object, findings[objectIdentity(object)])
+		suite.Tests++
+		if testCase.Failure != nil {
+			suite.Failures++
+		} else if testCase.Skipped != nil {
+			suite.Skipped++
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	report := junitTestSuites{Name: "lamb"}
+	var names []string
+	for name := range suites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		suite := suites[name]
+		sort.SliceStable(suite.Cases, func(i, j int) bool {
+			return suite.Cases[i].Line < suite.Cases[j].Line
+		})
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Skipped += suite.Skipped
+		report.Suites = append(report.Suites, *suite)
+	}
+	return report
+}
@@ -0,0 +1,121 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstance_IsVersioned_trackObjects(t *testing.T) {
+	instance := &Instance{
+		DeprecatedVersions: []Version{
+			{Name: "extensions/v1beta1", Kind: "Deployment", DeprecatedIn: "v1.9.0", RemovedIn: "v1.16.0", Component: "k8s"},
+		},
+	}
+	data := []byte(`apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: old
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: new
+  namespace: web
+`)
+	outputs, err := instance.IsVersioned(data)
+	assert.NoError(t, err)
+	assert.Len(t, outputs, 1)
+
+	instance.TrackObjects = true
+	outputs, err = instance.IsVersioned(data)
+	assert.NoError(t, err)
+	assert.Len(t, outputs, 2)
+	assert.Equal(t, &Output{Name: "new", Namespace: "web", APIVersion: &Version{Name: "apps/v1", Kind: "Deployment"}, Document: 2, Line: 6, Column: 1}, outputs[1])
+}
+
+func TestInstance_junit(t *testing.T) {
+	deployment := &Version{Name: "extensions/v1beta1", Kind: "Deployment", DeprecatedIn: "v1.9.0", RemovedIn: "v1.16.0", ReplacementAPI: "apps/v1", ReplacementAvailableIn: "v1.9.0", Component: "k8s"}
+	psp := &Version{Name: "policy/v1beta1", Kind: "PodSecurityPolicy", DeprecatedIn: "v1.21.0", RemovedIn: "v1.25.0", ReplacementAvailableIn: "v1.21.0", Component: "k8s"}
+
+	removed := &Output{Name: "old", FilePath: "deploy.yaml", Line: 1, APIVersion: deployment, Deprecated: true, Removed: true}
+	deprecated := &Output{Name: "restricted", FilePath: "deploy.yaml", Line: 20, APIVersion: psp, Deprecated: true}
+	clean := &Output{Name: "new", FilePath: "deploy.yaml", Line: 10, APIVersion: &Version{Name: "apps/v1", Kind: "Deployment"}}
+	suppressed := &Output{Name: "release/old", Namespace: "apps", APIVersion: deployment, Deprecated: true, Removed: true, Suppressed: true, SuppressedBy: "comment # lamb:ignore"}
+
+	newInstance := func() *Instance {
+		return &Instance{
+			Outputs:        []*Output{removed, deprecated, suppressed},
+			Objects:        []*Output{removed, clean, deprecated, suppressed},
+			TargetVersions: map[string]string{"k8s": "v1.22.0"},
+		}
+	}
+
+	report := newInstance().junit()
+	assert.Equal(t, 4, report.Tests)
+	assert.Equal(t, 2, report.Failures)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, []string{"apps", "deploy.yaml"}, []string{report.Suites[0].Name, report.Suites[1].Name})
+
+	assert.Equal(t, junitTestCase{
+		Name:      "Deployment release/old (extensions/v1beta1)",
+		ClassName: "apps",
+		Skipped:   &junitSkipped{Message: "Deployment release/old uses extensions/v1beta1, which is removed. Replace it with apps/v1. Suppressed by comment # lamb:ignore."},
+	}, report.Suites[0].Cases[0])
+
+	cases := report.Suites[1].Cases
+	assert.Equal(t, []string{
+		"Deployment old (extensions/v1beta1)",
+		"Deployment new (apps/v1)",
+		"PodSecurityPolicy restricted (policy/v1beta1)",
+	}, []string{cases[0].Name, cases[1].Name, cases[2].Name})
+	assert.Equal(t, &junitFailure{
+		Message: "Deployment old uses extensions/v1beta1, which is removed. Replace it with apps/v1.",
+		Type:    "removed",
+		Text:    "Deployment old uses extensions/v1beta1, which is removed. Replace it with apps/v1.",
+	}, cases[0].Failure)
+	assert.Nil(t, cases[1].Failure)
+	assert.Nil(t, cases[1].Skipped)
+	assert.Equal(t, "deprecated", cases[2].Failure.Type)
+
+	// deprecated objects are skipped when the policy does not fail on them, removed objects still fail
+	instance := newInstance()
+	instance.Policy, _ = FailOnPolicy([]string{"none"})
+	report = instance.junit()
+	assert.Equal(t, 1, report.Failures)
+	assert.Equal(t, 2, report.Skipped)
+	assert.Equal(t, "Deployment old uses extensions/v1beta1, which is removed. Replace it with apps/v1.", report.Suites[1].Cases[0].Failure.Message)
+	assert.Equal(t, &junitSkipped{Message: "PodSecurityPolicy restricted uses policy/v1beta1, which is deprecated."}, report.Suites[1].Cases[2].Skipped)
+}
+
+func TestInstance_junit_xml(t *testing.T) {
+	instance := &Instance{
+		Outputs: []*Output{
+			{Name: "web", FilePath: "ingress.yaml", Line: 3, APIVersion: &Version{Name: "extensions/v1beta1", Kind: "Ingress", Component: "platform"}, Rule: "ingress-class", Message: "use spec.ingressClassName", Severity: SeverityWarn},
+		},
+	}
+	data, err := xml.MarshalIndent(instance.junit(), "", "  ")
+	assert.NoError(t, err)
+	assert.Equal(t, `<testsuites name="lamb" tests="1" failures="1" skipped="0">
+  <testsuite name="ingress.yaml" tests="1" failures="1" skipped="0">
+    <testcase name="Ingress web (extensions/v1beta1)" classname="ingress.yaml" file="ingress.yaml" line="3">
+      <failure message="Ingress web: use spec.ingressClassName" type="rule">Ingress web: use spec.ingressClassName</failure>
+    </testcase>
+  </testsuite>
+</testsuites>`, string(data))
+}
@@ -0,0 +1,212 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// VersionActionAdd adds a version that does not exist yet
+	VersionActionAdd = "add"
+	// VersionActionOverride overrides the values of an existing version
+	VersionActionOverride = "override"
+	// VersionActionRemove removes an existing version
+	VersionActionRemove = "remove"
+
+	// DefaultVersionsOrigin is the origin of the versions built into lamb
+	DefaultVersionsOrigin = "built-in"
+)
+
+var versionActions = []string{VersionActionAdd, VersionActionOverride, VersionActionRemove}
+
+// UnmarshalYAML decodes a version and records which keys an override sets,
+// so that an override only replaces those values
+func (v *Version) UnmarshalYAML(node *yaml.Node) error {
+	type plain Version
+	err := node.Decode((*plain)(v))
+	if err != nil {
+		return err
+	}
+	v.overrides = nil
+	if v.Action == VersionActionOverride && node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v.overrides = append(v.overrides, node.Content[i].Value)
+		}
+	}
+	return nil
+}
+
+// overriddenBy returns v with the values set in override. Keys missing from the override
+// keep the value of v, and fields are replaced as a whole. An override that was not decoded
+// from a versions file replaces every value.
+func (v Version) overriddenBy(override Version) Version {
+	origin := v.Origin
+	if override.Origin != "" {
+		origin = override.Origin
+	}
+	merged := v
+	if override.overrides == nil {
+		merged = override
+	}
+	for _, key := range override.overrides {
+		switch key {
+		case "deprecated-in":
+			merged.DeprecatedIn = override.DeprecatedIn
+		case "removed-in":
+			merged.RemovedIn = override.RemovedIn
+		case "replacement-api":
+			merged.ReplacementAPI = override.ReplacementAPI
+		case "replacement-available-in":
+			merged.ReplacementAvailableIn = override.ReplacementAvailableIn
+		case "component":
+			merged.Component = override.Component
+		case "fields":
+			merged.Fields = override.Fields
+		}
+	}
+	merged.Action = ""
+	merged.Origin = origin
+	merged.overrides = nil
+	return merged
+}
+
+// VersionFilePaths expands every directory in paths to the .yaml, .yml and .json files
+// directly within it, sorted by name. Other paths and URLs are returned as they are.
+func VersionFilePaths(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		if IsURL(path) {
+			files = append(files, path)
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, entry := range entries {
+			switch filepath.Ext(entry.Name()) {
+			case ".yaml", ".yml", ".json":
+				if !entry.IsDir() {
+					files = append(files, filepath.Join(path, entry.Name()))
+					found = true
+				}
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no versions files found in %s", path)
+		}
+	}
+	return files, nil
+}
+
+// MergeVersionFiles merges the versions files in paths into the defaults, in order, with
+// CombineAdditionalVersions. Directories are expanded with VersionFilePaths, and URLs are
+// downloaded with fetcher, or a VersionFetcher with the defaults if it is nil. Every version
+// is annotated with the file it was last added or overridden by, or DefaultVersionsOrigin.
+// If strict is set, every file must pass ValidateVersionFile.
+//
+// The target versions of the defaults take precedence over those of the files, and the
+// target versions of a file take precedence over those of the files before it. Platform
+// versions and component probes of a file take precedence over those of the defaults and
+// the files before it.
+func MergeVersionFiles(paths []string, defaults VersionFile, strict bool, fetcher *VersionFetcher) (*VersionFile, error) {
+	if fetcher == nil {
+		fetcher = &VersionFetcher{}
+	}
+	files, err := VersionFilePaths(paths)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]Version, len(defaults.DeprecatedVersions))
+	for i, version := range defaults.DeprecatedVersions {
+		if version.Origin == "" {
+			version.Origin = DefaultVersionsOrigin
+		}
+		versions[i] = version
+	}
+	platforms := defaults.Platforms.merge(nil)
+	probes := defaults.ComponentProbes.merge(nil)
+	targetVersions := map[string]string{}
+	for _, file := range files {
+		klog.V(2).Infof("looking for versions file: %s", file)
+		var data []byte
+		if IsURL(file) {
+			data, err = fetcher.Fetch(file)
+		} else {
+			data, err = os.ReadFile(file)
+		}
+		if err != nil {
+			return nil, err
+		}
+		var additional *VersionFile
+		if strict {
+			additional, err = UnMarshalVersionFileStrict(file, data, mergeTargetVersions(targetVersions, defaults.TargetVersions))
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			additional, err = UnMarshalVersionFile(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", file, err.Error())
+			}
+		}
+		for i := range additional.DeprecatedVersions {
+			additional.DeprecatedVersions[i].Origin = file
+		}
+		versions, err = CombineAdditionalVersions(additional.DeprecatedVersions, versions)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", file, err.Error())
+		}
+		for component, version := range additional.TargetVersions {
+			klog.V(2).Infof("received target version from %s: %s %s", file, component, version)
+			targetVersions[component] = version
+		}
+		platforms = platforms.merge(additional.Platforms)
+		probes = probes.merge(additional.ComponentProbes)
+	}
+	return &VersionFile{
+		DeprecatedVersions: versions,
+		TargetVersions:     mergeTargetVersions(targetVersions, defaults.TargetVersions),
+		Platforms:          platforms,
+		ComponentProbes:    probes,
+	}, nil
+}
+
+// mergeTargetVersions returns the target versions of base with those of overrides on top
+func mergeTargetVersions(base map[string]string, overrides map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(overrides))
+	for component, version := range base {
+		merged[component] = version
+	}
+	for component, version := range overrides {
+		merged[component] = version
+	}
+	return merged
+}
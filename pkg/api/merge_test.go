@@ -0,0 +1,149 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var mergeDefaults = []Version{
+	{Name: "extensions/v1beta1", Kind: "Deployment", DeprecatedIn: "v1.9.0", RemovedIn: "v1.16.0", ReplacementAPI: "apps/v1", Component: "k8s"},
+	{Name: "extensions/v1beta1", Kind: "Ingress", DeprecatedIn: "v1.14.0", RemovedIn: "v1.22.0", ReplacementAPI: "networking.k8s.io/v1", Component: "k8s"},
+}
+
+func writeVersionFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(data), 0644))
+	}
+	return dir
+}
+
+func TestMergeVersionFiles(t *testing.T) {
+	dir := writeVersionFiles(t, map[string]string{
+		"vendor.yaml": `deprecated-versions:
+- version: extensions/v1beta1
+  kind: Ingress
+  removed-in: v1.20.0
+  action: override
+- version: extensions/v1beta1
+  kind: Deployment
+  action: remove
+target-versions:
+  k8s: v1.20.0
+  vendor: v2.0.0
+  istio: v1.10.0
+`,
+		"conf.d/10-istio.yaml": `deprecated-versions:
+- version: networking.istio.io/v1alpha3
+  kind: Gateway
+  removed-in: v1.10.0
+  component: istio
+`,
+		"conf.d/20-istio.yml": `deprecated-versions:
+- version: networking.istio.io/v1alpha3
+  kind: Gateway
+  deprecated-in: v1.9.0
+  action: override
+target-versions:
+  istio: v1.11.0
+`,
+		"conf.d/README.md": "not a versions file",
+	})
+	vendor := filepath.Join(dir, "vendor.yaml")
+	confDir := filepath.Join(dir, "conf.d")
+
+	got, err := MergeVersionFiles([]string{vendor, confDir}, VersionFile{DeprecatedVersions: mergeDefaults, TargetVersions: map[string]string{"k8s": "v1.22.0"}}, true, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []Version{
+		{Name: "extensions/v1beta1", Kind: "Ingress", DeprecatedIn: "v1.14.0", RemovedIn: "v1.20.0", ReplacementAPI: "networking.k8s.io/v1", Component: "k8s", Origin: vendor},
+		{Name: "networking.istio.io/v1alpha3", Kind: "Gateway", DeprecatedIn: "v1.9.0", RemovedIn: "v1.10.0", Component: "istio", Origin: filepath.Join(confDir, "20-istio.yml")},
+	}, got.DeprecatedVersions)
+	// the built-in target wins over files, and later files win over earlier ones
+	assert.Equal(t, map[string]string{"k8s": "v1.22.0", "vendor": "v2.0.0", "istio": "v1.11.0"}, got.TargetVersions)
+	// the defaults are not modified
+	assert.Equal(t, "v1.22.0", mergeDefaults[1].RemovedIn)
+	assert.Equal(t, "", mergeDefaults[1].Origin)
+
+	got, err = MergeVersionFiles(nil, VersionFile{DeprecatedVersions: mergeDefaults}, false, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultVersionsOrigin, got.DeprecatedVersions[0].Origin)
+
+	_, err = MergeVersionFiles([]string{confDir, vendor, vendor}, VersionFile{DeprecatedVersions: mergeDefaults}, false, nil)
+	assert.EqualError(t, err, vendor+": cannot remove Deployment extensions/v1beta1, it is not in the versions before it")
+}
+
+func TestMergeVersionFiles_strict(t *testing.T) {
+	dir := writeVersionFiles(t, map[string]string{
+		"versions.yaml": `deprecated-versions:
+- version: extensions/v1beta1
+  kind: Ingress
+  removed-in: 1.20.0
+  action: override
+`,
+	})
+	file := filepath.Join(dir, "versions.yaml")
+	_, err := MergeVersionFiles([]string{file}, VersionFile{DeprecatedVersions: mergeDefaults}, false, nil)
+	assert.NoError(t, err)
+	_, err = MergeVersionFiles([]string{file}, VersionFile{DeprecatedVersions: mergeDefaults}, true, nil)
+	assert.EqualError(t, err, file+`:4:15: removed-in "1.20.0" is not valid semver (missing the leading 'v')`)
+}
+
+func TestVersionFilePaths(t *testing.T) {
+	dir := writeVersionFiles(t, map[string]string{
+		"b.yaml":         "",
+		"a.json":         "",
+		"c.txt":          "",
+		"nested/d.yaml":  "",
+		"empty/.gitkeep": "",
+	})
+	got, err := VersionFilePaths([]string{filepath.Join(dir, "nested", "d.yaml"), dir})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "nested", "d.yaml"),
+		filepath.Join(dir, "a.json"),
+		filepath.Join(dir, "b.yaml"),
+	}, got)
+
+	_, err = VersionFilePaths([]string{filepath.Join(dir, "empty")})
+	assert.EqualError(t, err, "no versions files found in "+filepath.Join(dir, "empty"))
+	_, err = VersionFilePaths([]string{filepath.Join(dir, "missing.yaml")})
+	assert.Error(t, err)
+}
+
+func TestVersion_UnmarshalYAML(t *testing.T) {
+	versions, _, err := UnMarshalVersions([]byte(`deprecated-versions:
+- version: extensions/v1beta1
+  kind: Ingress
+  removed-in: ""
+  action: override
+- version: apps/v1
+  kind: Deployment
+  removed-in: v1.16.0
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"version", "kind", "removed-in", "action"}, versions[0].overrides)
+	assert.Nil(t, versions[1].overrides)
+
+	// an override can clear a value
+	got := mergeDefaults[1].overriddenBy(versions[0])
+	assert.Equal(t, Version{Name: "extensions/v1beta1", Kind: "Ingress", DeprecatedIn: "v1.14.0", ReplacementAPI: "networking.k8s.io/v1", Component: "k8s"}, got)
+}
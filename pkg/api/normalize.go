@@ -0,0 +1,74 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// NormalizeVersion returns the base version of a version string as semver with a leading 'v'
+// and all three of major, minor and patch. The leading 'v' is optional, a missing minor or
+// patch is 0, and any pre-release or build suffix is dropped, so that the server versions of
+// managed distributions compare as the Kubernetes version they are based on:
+//
+//	1.27                -> v1.27.0
+//	v1.27.3-eks-2d98532 -> v1.27.3
+//	v1.27.3-gke.1700    -> v1.27.3
+//	v1.26.5+k3s1        -> v1.26.5
+//	v1.27.6+f67aeb3     -> v1.27.6 (OpenShift)
+//
+// ok is false if version does not start with a numeric major version.
+func NormalizeVersion(version string) (normalized string, ok bool) {
+	base := strings.TrimSpace(version)
+	base = strings.TrimPrefix(strings.TrimPrefix(base, "v"), "V")
+	if i := strings.IndexAny(base, "-+"); i >= 0 {
+		base = base[:i]
+	}
+	parts := strings.Split(base, ".")
+	if len(parts) > 3 {
+		return "", false
+	}
+	numbers := [3]int{}
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return "", false
+		}
+		numbers[i] = number
+	}
+	normalized = fmt.Sprintf("v%d.%d.%d", numbers[0], numbers[1], numbers[2])
+	if !semver.IsValid(normalized) {
+		return "", false
+	}
+	return normalized, true
+}
+
+// IsValidVersion returns whether version can be normalized with NormalizeVersion
+func IsValidVersion(version string) bool {
+	_, ok := NormalizeVersion(version)
+	return ok
+}
+
+// compareVersions compares the normalized versions of a and b like semver.Compare.
+// A version that cannot be normalized is less than any that can.
+func compareVersions(a string, b string) int {
+	normalizedA, _ := NormalizeVersion(a)
+	normalizedB, _ := NormalizeVersion(b)
+	return semver.Compare(normalizedA, normalizedB)
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+		wantOK  bool
+	}{
+		{version: "v1.27.3", want: "v1.27.3", wantOK: true},
+		{version: "1.27.3", want: "v1.27.3", wantOK: true},
+		{version: "1.27", want: "v1.27.0", wantOK: true},
+		{version: "v1.27", want: "v1.27.0", wantOK: true},
+		{version: "v1", want: "v1.0.0", wantOK: true},
+		{version: " v1.27.3\n", want: "v1.27.3", wantOK: true},
+		{version: "v1.27.3-eks-2d98532", want: "v1.27.3", wantOK: true},
+		{version: "v1.27.3-gke.1700", want: "v1.27.3", wantOK: true},
+		{version: "v1.27.7-gke.1121002", want: "v1.27.7", wantOK: true},
+		{version: "v1.26.5+k3s1", want: "v1.26.5", wantOK: true},
+		{version: "v1.27.10+rke2r1", want: "v1.27.10", wantOK: true},
+		{version: "v1.27.6+f67aeb3", want: "v1.27.6", wantOK: true},
+		{version: "v1.28.0-rc.1", want: "v1.28.0", wantOK: true},
+		{version: "v01.027.3", want: "v1.27.3", wantOK: true},
+		{version: "", wantOK: false},
+		{version: "v", wantOK: false},
+		{version: "foo", wantOK: false},
+		{version: "v1.x", wantOK: false},
+		{version: "1.27.3.4", wantOK: false},
+		{version: "latest", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got, ok := NormalizeVersion(tt.version)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantOK, IsValidVersion(tt.version))
+		})
+	}
+}
+
+func Test_compareVersions(t *testing.T) {
+	assert.Equal(t, 0, compareVersions("v1.27.3-eks-2d98532", "1.27.3"))
+	assert.Equal(t, 0, compareVersions("1.27", "v1.27.0"))
+	assert.Equal(t, 1, compareVersions("v1.26.5+k3s1", "v1.25"))
+	assert.Equal(t, -1, compareVersions("v1.21.14-gke.700", "v1.22.0"))
+	assert.Equal(t, -1, compareVersions("foo", "v0.0.0"))
+}
+
+func TestVersion_vendorTargetVersions(t *testing.T) {
+	version := &Version{DeprecatedIn: "v1.19.0", RemovedIn: "1.22", ReplacementAvailableIn: "v1.19", Component: "k8s"}
+	for _, target := range []string{"1.22", "v1.22", "v1.22.17-eks-c12679a", "v1.22.0+k3s1"} {
+		targetVersions := map[string]string{"k8s": target}
+		assert.True(t, version.isDeprecatedIn(targetVersions), target)
+		assert.True(t, version.isRemovedIn(targetVersions), target)
+		assert.True(t, version.isReplacementAvailableIn(targetVersions), target)
+	}
+	targetVersions := map[string]string{"k8s": "v1.21.14-eks-c12679a"}
+	assert.True(t, version.isDeprecatedIn(targetVersions))
+	assert.False(t, version.isRemovedIn(targetVersions))
+}
@@ -17,10 +17,12 @@ package api
 import (
 	"encoding/csv"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"os"
 	"sort"
 	"text/tabwriter"
+	"time"
 
 	"github.com/olekukonko/tablewriter"
 
@@ -36,11 +38,19 @@ type Output struct {
 	Name string `json:"name,omitempty" yaml:"name,omitempty"`
 	// FilePath is the full path of the file if the output came from a file
 	FilePath string `json:"filePath,omitempty" yaml:"filePath,omitempty"`
+	// Document is the number of the document in the source, counting from one, if known
+	Document int `json:"document,omitempty" yaml:"document,omitempty"`
+	// Line is the line of the apiVersion key in the source, if known
+	Line int `json:"line,omitempty" yaml:"line,omitempty"`
+	// Column is the column of the apiVersion key in the source, if known
+	Column int `json:"column,omitempty" yaml:"column,omitempty"`
 	// Namespace is the namespace that the object is in
 	// The output may resolve this to UNKNOWN if there is no way of determining it
 	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
 	// APIVersion is the version object corresponding to this output
 	APIVersion *Version `json:"api,omitempty" yaml:"api,omitempty"`
+	// Field is the path of the deprecated field if the output is for a field rather than the whole apiVersion
+	Field string `json:"field,omitempty" yaml:"field,omitempty"`
 
 	APIType string `json:"type,omitempty" yaml:"type,omitempty"`
 	
@@ -53,8 +63,22 @@ type Output struct {
 	Removed bool `json:"removed" yaml:"removed"`
 	// ReplacementAvailable is a boolean indicating whether or not the replacement is available
 	ReplacementAvailable bool `json:"replacementAvailable" yaml:"replacementAvailable"`
+	// Suppressed is a boolean indicating whether or not the output is an accepted finding, left out of the return code
+	Suppressed bool `json:"suppressed,omitempty" yaml:"suppressed,omitempty"`
+	// SuppressedBy describes what suppressed the output
+	SuppressedBy string `json:"suppressedBy,omitempty" yaml:"suppressedBy,omitempty"`
+	// Severity is the severity of the output from the policy or the rule, if there is one
+	Severity Severity `json:"severity,omitempty" yaml:"severity,omitempty"`
+	// Rule is the name of the rule that matched, if the output is for a rule rather than a deprecated version
+	Rule string `json:"rule,omitempty" yaml:"rule,omitempty"`
+	// Message is the message of the rule that matched
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// Change is how the output changed since a previous report, see Compare
+	Change Change `json:"change,omitempty" yaml:"change,omitempty"`
 	// CustomColumns is a list of column headers to be displayed with -ocustom or -omarkdown
 	CustomColumns []string `json:"-" yaml:"-"`
+	// ignore is the ignore rule of the object the output came from, see ignoreRuleOf
+	ignore *ignoreRule
 }
 
 // Instance is an instance of the API. This holds configuration for a "run" of lamb
@@ -66,20 +90,50 @@ type Instance struct {
 	OnlyShowRemoved               bool              `json:"-" yaml:"-"`
 	NoHeaders                     bool              `json:"-" yaml:"-"`
 	OutputFormat                  string            `json:"-" yaml:"-"`
+	// OutputTemplate is the template of -o go-template and -o jsonpath, see SplitTemplateOutput
+	OutputTemplate string `json:"-" yaml:"-"`
 	TargetVersions                map[string]string `json:"target-versions,omitempty" yaml:"target-versions,omitempty"`
 	DeprecatedVersions            []Version         `json:"-" yaml:"-"`
 	CustomColumns                 []string          `json:"-" yaml:"-"`
 	Components                    []string          `json:"-" yaml:"-"`
+	// Baseline is the list of accepted findings, see ApplyBaseline
+	Baseline *Baseline `json:"-" yaml:"-"`
+	// Policy sets the severity and exit code of outputs, see GetReturnCode
+	Policy *Policy `json:"-" yaml:"-"`
+	// Rules are checked against every manifest, see IsVersioned
+	Rules *Rules `json:"-" yaml:"-"`
+	// CompareTo is a previous report that DisplayOutput compares the outputs to, see Compare
+	CompareTo *Instance `json:"-" yaml:"-"`
+	// TrackObjects makes IsVersioned return an output for every object without a finding,
+	// so that -o junit can report the objects that pass
+	TrackObjects bool `json:"-" yaml:"-"`
+	// Objects is an output for each object scanned, with or without findings, recorded by
+	// FilterOutput if TrackObjects is set
+	Objects []*Output `json:"-" yaml:"-"`
+	// ShowSuppressed shows the outputs suppressed by an ignore annotation or comment
+	ShowSuppressed bool `json:"-" yaml:"-"`
+	// hiddenSuppressed is the number of suppressed outputs left out by FilterOutput
+	hiddenSuppressed int
+	// compared is whether the outputs have been compared to a previous report, see Compare
+	compared bool
+	// index is the lookup index of DeprecatedVersions, see BuildIndex
+	index *versionIndex
 }
 
 // DisplayOutput prints the output based on desired variables
 func (instance *Instance) DisplayOutput() error {
+	if instance.CompareTo != nil && !instance.compared {
+		instance.FilterOutput()
+		instance.Compare(instance.CompareTo)
+	}
 	if len(instance.Outputs) == 0 && (instance.OutputFormat == "normal" || instance.OutputFormat == "wide") {
 		fmt.Println("There were no resources found with known deprecated apiVersions.")
 		return nil
 	}
 
-	instance.FilterOutput()
+	if !instance.compared {
+		instance.FilterOutput()
+	}
 	var err error
 	var outData []byte
 	switch instance.OutputFormat {
@@ -118,6 +172,38 @@ func (instance *Instance) DisplayOutput() error {
 			return err
 		}
 		fmt.Println(string(outData))
+	case "sarif":
+		outData, err = json.Marshal(instance.sarif())
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(outData))
+	case "junit":
+		outData, err = xml.MarshalIndent(instance.junit(), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(xml.Header + string(outData))
+	case "html":
+		err = instance.writeHTML(os.Stdout)
+		if err != nil {
+			return err
+		}
+	case "github":
+		for _, command := range instance.github() {
+			fmt.Println(command)
+		}
+	case "gitlab-codequality":
+		outData, err = json.Marshal(instance.gitlabCodeQuality())
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(outData))
+	case TemplateOutput, JSONPathOutput:
+		err = instance.writeTemplate(os.Stdout)
+		if err != nil {
+			return err
+		}
 	case "markdown":
 		var c columnList
 		if len(instance.CustomColumns) >= 1 {
@@ -155,11 +241,14 @@ func (instance *Instance) DisplayOutput() error {
 
 // FilterOutput filters the outputs that get printed
 // first it fills out the Deprecated and Removed booleans
-// then it returns the outputs that are either deprecated or removed
-// and in the component list
+// then it returns the outputs that are either deprecated or removed,
+// or matched a rule, and in the component list
 // additionally, if instance.OnlyShowDeprecated is true, it will remove the
 // apiVersions that are deprecated but not removed
 func (instance *Instance) FilterOutput() {
+	if instance.TrackObjects {
+		instance.Objects = objectsOf(instance.Outputs)
+	}
 	var usableOutputs []*Output
 	for _, output := range instance.Outputs {
 		output.Deprecated = output.APIVersion.isDeprecatedIn(instance.TargetVersions)
@@ -176,7 +265,7 @@ func (instance *Instance) FilterOutput() {
 		output.ReplacementAvailable = output.APITypes.isReplacementAvailableIn(instance.TargetTypes)
 		switch instance.OnlyShowRemoved {
 		case false:
-			if output.Deprecated || output.Removed {
+			if output.Deprecated || output.Removed || output.Rule != "" {
 				if StringInSlice(output.APIVersion.Component, instance.Components) {
 					usableOutputs = append(usableOutputs, output)
 				if StringInSlice(output.APICAll.Component, instance.Components) {
@@ -200,6 +289,9 @@ func (instance *Instance) FilterOutput() {
 		}
 	}
 	instance.Outputs = usableOutputs
+	instance.applyIgnoreRules(time.Now())
+	instance.ApplyBaseline()
+	instance.applyPolicy()
 }
 
 // removeDeprecatedOnly is a list replacement operation
@@ -323,58 +415,8 @@ func (instance *Instance) csvOut(columns columnList) (*csv.Writer, error) {
 	return csvWriter, nil
 }
 
-// GetReturnCode checks for deprecated versions and returns a code.
-// takes a boolean to ignore any errors.
-// exit 2 - version deprecated
-// exit 3 - version removed
-// exit 4 - replacement is unavailable in target version
+// GetReturnCode returns the exit code of the NEW, unsuppressed outputs under the policy of
+// the instance, or the default policy if it has none, see Policy.returnCode and defaultPolicy.
 func (instance *Instance) GetReturnCode() int {
-	returnCode := 0
-	var deprecations int
-	var removals int
-	var unavailableReplacements int
-	for _, output := range instance.Outputs {
-		if output.APIVersion.isRemovedIn(instance.TargetVersions) {
-			removals = removals + 1
-		and if output.APICAll.isRemovedIn(instance.TargetCalls) {
-			removals = removals + 1
-		and if output.APITypes.isRemovedIn(instance.TargetTypes) {
-			removals = removals + 1
-		}
-		if output.APIVersion.isDeprecatedIn(instance.TargetVersions) {
-			if output.APIVersion.isReplacementAvailableIn(instance.TargetVersions) || !instance.IgnoreUnavailableReplacements {
-				deprecations = deprecations + 1
-			}
-		}
-		if output.APICall.isDeprecatedIn(instance.TargetCalls) {
-			if output.APICAll.isReplacementAvailableIn(instance.TargetCalls) || !instance.IgnoreUnavailableReplacements {
-				deprecations = deprecations + 1
-			}
-		}
-
-		if output.APITypes.isDeprecatedIn(instance.TargetTypes) {
-			if output.APITypes.isReplacementAvailableIn(instance.TargetTypes) || !instance.IgnoreUnavailableReplacements {
-				deprecations = deprecations + 1
-			}
-		}
-
-		if !output.APIVersion.isReplacementAvailableIn(instance.TargetVersions) {
-			unavailableReplacements = unavailableReplacements + 1
-		if !output.APICAll.isReplacementAvailableIn(instance.TargetCalls) {
-			unavailableReplacements = unavailableReplacements + 1
-		if !output.APITypes.isReplacementAvailableIn(instance.TargetTypes) {
-			unavailableReplacements = unavailableReplacements + 1
-		}
-	}
-
-	if deprecations > 0 && !instance.IgnoreDeprecations {
-		returnCode = 2
-	}
-	if removals > 0 && !instance.IgnoreRemovals {
-		returnCode = 3
-	}
-	if unavailableReplacements > 0 && !instance.IgnoreUnavailableReplacements {
-		returnCode = 4
-	}
-	return returnCode
+	return instance.policy().returnCode(instance.newOutputs(), instance.TargetVersions)
 }
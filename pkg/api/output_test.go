@@ -176,6 +176,38 @@ func ExampleInstance_DisplayOutput_custom() {
 	// <EXAMPLE>-------- some name two-- v1.0.0--------- true-------- apps/v1------ extensions/v1beta1-- Deployment-- foo-------- <EXAMPLE>-----
 }
 
+func ExampleInstance_DisplayOutput_custom_lineColumn() {
+	instance := &Instance{
+		TargetVersions: map[string]string{
+			"foo": "v1.0.0",
+		},
+		Outputs: []*Output{
+			{
+				Name:     "some name three",
+				FilePath: "path-to-file",
+				Line:     12,
+				Column:   3,
+				APIVersion: &Version{
+					Name:         "extensions/v1beta1",
+					Kind:         "Deployment",
+					DeprecatedIn: "v1.0.0",
+					Component:    "foo",
+				},
+			},
+			testOutput2,
+		},
+		OutputFormat:  "custom",
+		Components:    []string{"foo"},
+		CustomColumns: []string{"FILEPATH", "LINE", "COLUMN", "NAME"},
+	}
+	_ = instance.DisplayOutput()
+
+	// Output:
+	// FILEPATH------ LINE------- COLUMN----- NAME-------------
+	// path-to-file-- 12--------- 3---------- some name three--
+	// <UNKNOWN>----- <UNKNOWN>-- <UNKNOWN>-- some name two----
+}
+
 func ExampleInstance_DisplayOutput_markdown() {
 	instance := &Instance{
 		TargetVersions: map[string]string{
@@ -402,9 +434,10 @@ func TestGetReturnCode(t *testing.T) {
 				outputs: []*Output{
 					{
 						APIVersion: &Version{
-							DeprecatedIn: "v1.0.0",
-							RemovedIn:    "v1.0.0",
-							Component:    "foo",
+							DeprecatedIn:           "v1.0.0",
+							RemovedIn:              "v1.1.0",
+							ReplacementAvailableIn: "v1.0.0",
+							Component:              "foo",
 						},
 					},
 				},
@@ -412,7 +445,7 @@ func TestGetReturnCode(t *testing.T) {
 				ignoreRemovals:               false,
 				ignoreReplacementUnavailable: false,
 			},
-			want: 4,
+			want: 2,
 		},
 		{
 			name: "version is deprecated ignore deprecations",
@@ -420,9 +453,10 @@ func TestGetReturnCode(t *testing.T) {
 				outputs: []*Output{
 					{
 						APIVersion: &Version{
-							DeprecatedIn: "v1.0.0",
-							RemovedIn:    "v1.0.0",
-							Component:    "foo",
+							DeprecatedIn:           "v1.0.0",
+							RemovedIn:              "v1.1.0",
+							ReplacementAvailableIn: "v1.0.0",
+							Component:              "foo",
 						},
 					},
 				},
@@ -430,7 +464,7 @@ func TestGetReturnCode(t *testing.T) {
 				ignoreRemovals:               false,
 				ignoreReplacementUnavailable: false,
 			},
-			want: 4,
+			want: 0,
 		},
 		{
 			name: "version is removed",
@@ -448,7 +482,7 @@ func TestGetReturnCode(t *testing.T) {
 				ignoreRemovals:               false,
 				ignoreReplacementUnavailable: false,
 			},
-			want: 4,
+			want: 3,
 		},
 		{
 			name: "version is removed and replacement is unavailable",
@@ -458,14 +492,14 @@ func TestGetReturnCode(t *testing.T) {
 						APIVersion: &Version{
 							DeprecatedIn:           "v1.0.0",
 							RemovedIn:              "v1.0.0",
-							ReplacementAvailableIn: "v1.0.0",
+							ReplacementAvailableIn: "v1.1.0",
 							Component:              "foo",
 						},
 					},
 				},
 				ignoreDeprecations:           false,
 				ignoreRemovals:               false,
-				ignoreReplacementUnavailable: true,
+				ignoreReplacementUnavailable: false,
 			},
 			want: 3,
 		},
@@ -476,14 +510,15 @@ func TestGetReturnCode(t *testing.T) {
 					{
 						APIVersion: &Version{
 							DeprecatedIn:           "v1.0.0",
-							RemovedIn:              "v1.0.0",
-							ReplacementAvailableIn: "v1.0.0",
+							RemovedIn:              "v1.1.0",
+							ReplacementAvailableIn: "v1.1.0",
 							Component:              "foo",
 						},
 					},
 				},
-				ignoreDeprecations: false,
-				ignoreRemovals:     false,
+				ignoreDeprecations:           false,
+				ignoreRemovals:               false,
+				ignoreReplacementUnavailable: false,
 			},
 			want: 4,
 		},
@@ -494,8 +529,8 @@ func TestGetReturnCode(t *testing.T) {
 					{
 						APIVersion: &Version{
 							DeprecatedIn:           "v1.0.0",
-							RemovedIn:              "v1.0.0",
-							ReplacementAvailableIn: "v1.0.0",
+							RemovedIn:              "v1.1.0",
+							ReplacementAvailableIn: "v1.1.0",
 							Component:              "foo",
 						},
 					},
@@ -506,6 +541,64 @@ func TestGetReturnCode(t *testing.T) {
 			},
 			want: 0,
 		},
+		{
+			name: "removal is not masked by an unavailable replacement",
+			args: args{
+				outputs: []*Output{
+					{
+						APIVersion: &Version{
+							DeprecatedIn: "v0.9.0",
+							Component:    "foo",
+						},
+					},
+					{
+						APIVersion: &Version{
+							DeprecatedIn: "v0.9.0",
+							RemovedIn:    "v1.0.0",
+							Component:    "foo",
+						},
+					},
+				},
+			},
+			want: 3,
+		},
+		{
+			name: "replacement is unavailable",
+			args: args{
+				outputs: []*Output{
+					{
+						APIVersion: &Version{
+							DeprecatedIn: "v0.9.0",
+							RemovedIn:    "v1.1.0",
+							Component:    "foo",
+						},
+					},
+				},
+			},
+			want: 4,
+		},
+		{
+			name: "removal is not masked by the order of outputs",
+			args: args{
+				outputs: []*Output{
+					{
+						APIVersion: &Version{
+							RemovedIn:              "v1.0.0",
+							ReplacementAvailableIn: "v1.0.0",
+							Component:              "foo",
+						},
+					},
+					{
+						APIVersion: &Version{
+							DeprecatedIn: "v1.0.0",
+							Component:    "foo",
+						},
+					},
+				},
+				ignoreReplacementUnavailable: true,
+			},
+			want: 3,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,200 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+// PatternType is how the version or kind of a deprecation rule is matched
+type PatternType string
+
+const (
+	// PatternExact matches the string as is
+	PatternExact PatternType = "exact"
+	// PatternGlob matches with * (any run of characters), ? (any single character) and [...] (a character class)
+	PatternGlob PatternType = "glob"
+	// PatternRegex matches a regular expression written between slashes, such as /^Flow.*$/.
+	// The expression must match the whole string.
+	PatternRegex PatternType = "regex"
+	// PatternAny is an empty kind, which matches every kind
+	PatternAny PatternType = "any"
+)
+
+// patternTypeOf returns the type of a version or kind pattern
+func patternTypeOf(pattern string) PatternType {
+	switch {
+	case pattern == "":
+		return PatternAny
+	case len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/"):
+		return PatternRegex
+	case strings.ContainsAny(pattern, "*?["):
+		return PatternGlob
+	default:
+		return PatternExact
+	}
+}
+
+var (
+	compiledPatterns     = map[string]*regexp.Regexp{}
+	compiledPatternsLock sync.RWMutex
+)
+
+// compilePattern returns the regular expression for a glob or regex pattern
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	compiledPatternsLock.RLock()
+	re, ok := compiledPatterns[pattern]
+	compiledPatternsLock.RUnlock()
+	if ok {
+		return re, nil
+	}
+
+	var expression string
+	switch patternTypeOf(pattern) {
+	case PatternRegex:
+		expression = "^(?:" + pattern[1:len(pattern)-1] + ")$"
+	case PatternGlob:
+		expression = "^" + globToRegex(pattern) + "$"
+	default:
+		expression = "^" + regexp.QuoteMeta(pattern) + "$"
+	}
+	re, err := regexp.Compile(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+	}
+
+	compiledPatternsLock.Lock()
+	compiledPatterns[pattern] = re
+	compiledPatternsLock.Unlock()
+	return re, nil
+}
+
+func globToRegex(glob string) string {
+	var expression strings.Builder
+	inClass := false
+	for _, r := range glob {
+		switch {
+		case inClass:
+			if r == ']' {
+				inClass = false
+			}
+			expression.WriteRune(r)
+		case r == '*':
+			expression.WriteString(".*")
+		case r == '?':
+			expression.WriteString(".")
+		case r == '[':
+			inClass = true
+			expression.WriteRune(r)
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return expression.String()
+}
+
+// matchPattern returns true if value matches the pattern.
+// Invalid patterns never match.
+func matchPattern(pattern string, value string) bool {
+	switch patternTypeOf(pattern) {
+	case PatternAny:
+		return true
+	case PatternExact:
+		return pattern == value
+	}
+	re, err := compilePattern(pattern)
+	if err != nil {
+		klog.V(3).Infof("%s", err.Error())
+		return false
+	}
+	return re.MatchString(value)
+}
+
+// patternSpecificity ranks how specific a pattern is. Exact patterns rank above globs,
+// globs above regular expressions, and regular expressions above an empty kind.
+// Within globs and regular expressions, the pattern with more literal characters ranks higher.
+func patternSpecificity(pattern string) (PatternType, int) {
+	patternType := patternTypeOf(pattern)
+	switch patternType {
+	case PatternGlob:
+		return patternType, len(pattern) - strings.Count(pattern, "*") - strings.Count(pattern, "?")
+	case PatternRegex:
+		return patternType, len(pattern) - 2
+	}
+	return patternType, len(pattern)
+}
+
+var patternRank = map[PatternType]int{
+	PatternExact: 3,
+	PatternGlob:  2,
+	PatternRegex: 1,
+	PatternAny:   0,
+}
+
+// comparePatterns returns a positive number if a is more specific than b,
+// a negative number if it is less specific and zero if they rank the same
+func comparePatterns(a string, b string) int {
+	aType, aLength := patternSpecificity(a)
+	bType, bLength := patternSpecificity(b)
+	if patternRank[aType] != patternRank[bType] {
+		return patternRank[aType] - patternRank[bType]
+	}
+	return aLength - bLength
+}
+
+// isMoreSpecific returns true if version a should be preferred over b when both match.
+// The version pattern decides first, then the kind pattern.
+func (a *Version) isMoreSpecific(b *Version) bool {
+	if c := comparePatterns(a.Name, b.Name); c != 0 {
+		return c > 0
+	}
+	return comparePatterns(a.Kind, b.Kind) > 0
+}
+
+// matchType describes how a version is matched, for display
+func (v *Version) matchType() string {
+	versionType := patternTypeOf(v.Name)
+	kindType := patternTypeOf(v.Kind)
+	var parts []string
+	if versionType != PatternExact {
+		parts = append(parts, "version "+string(versionType))
+	}
+	if kindType != PatternExact {
+		parts = append(parts, "kind "+string(kindType))
+	}
+	if len(parts) == 0 {
+		return string(PatternExact)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// validatePatterns returns an error if the version or kind of a version is an invalid pattern
+func (v *Version) validatePatterns() error {
+	for _, pattern := range []string{v.Name, v.Kind} {
+		switch patternTypeOf(pattern) {
+		case PatternGlob, PatternRegex:
+			_, err := compilePattern(pattern)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
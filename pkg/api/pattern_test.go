@@ -0,0 +1,79 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_patternTypeOf(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    PatternType
+	}{
+		{pattern: "", want: PatternAny},
+		{pattern: "apps/v1", want: PatternExact},
+		{pattern: "flowcontrol.apiserver.k8s.io/*", want: PatternGlob},
+		{pattern: "v1beta?", want: PatternGlob},
+		{pattern: "/^Flow.*$/", want: PatternRegex},
+		{pattern: "/", want: PatternExact},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			assert.Equal(t, tt.want, patternTypeOf(tt.pattern))
+		})
+	}
+}
+
+func Test_matchPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		value   string
+		want    bool
+	}{
+		{name: "any", pattern: "", value: "Deployment", want: true},
+		{name: "exact", pattern: "apps/v1", value: "apps/v1", want: true},
+		{name: "exact mismatch", pattern: "apps/v1", value: "apps/v1beta1", want: false},
+		{name: "glob prefix", pattern: "*.internal.example.com/v1alpha1", value: "widgets.internal.example.com/v1alpha1", want: true},
+		{name: "glob dots are literal", pattern: "*.internal.example.com/v1alpha1", value: "widgets.internalxexample.com/v1alpha1", want: false},
+		{name: "glob suffix", pattern: "flowcontrol.apiserver.k8s.io/*", value: "flowcontrol.apiserver.k8s.io/v1beta3", want: true},
+		{name: "glob single character", pattern: "v1beta?", value: "v1beta2", want: true},
+		{name: "glob class", pattern: "v1beta[12]", value: "v1beta3", want: false},
+		{name: "regex is anchored", pattern: "/Flow/", value: "FlowSchema", want: false},
+		{name: "regex", pattern: "/Flow.*|PriorityLevel.*/", value: "PriorityLevelConfiguration", want: true},
+		{name: "invalid regex", pattern: "/(/", value: "(", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchPattern(tt.pattern, tt.value))
+		})
+	}
+}
+
+func Test_comparePatterns(t *testing.T) {
+	assert.Greater(t, comparePatterns("apps/v1", "apps/*"), 0)
+	assert.Greater(t, comparePatterns("*.internal.example.com/*", "*.example.com/*"), 0)
+	assert.Greater(t, comparePatterns("*/v1", "/.*/v1/"), 0)
+	assert.Greater(t, comparePatterns("/Flow.*/", ""), 0)
+	assert.Equal(t, 0, comparePatterns("apps/*", "apps/*"))
+}
+
+func TestUnMarshalVersions_invalidPattern(t *testing.T) {
+	_, _, err := UnMarshalVersions([]byte("deprecated-versions:\n- version: /(/\n  kind: Deployment\n  component: k8s\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,226 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/mod/semver"
+	"gopkg.in/yaml.v3"
+)
+
+// UpgradePlan is the result of evaluating the outputs at every minor version
+// between the from and to versions of each component
+type UpgradePlan struct {
+	From  map[string]string `json:"from" yaml:"from"`
+	To    map[string]string `json:"to" yaml:"to"`
+	Steps []UpgradeStep     `json:"steps" yaml:"steps"`
+}
+
+// UpgradeStep holds the outputs that change state when a component is upgraded to Version.
+// The first step of each component is the from version and holds everything that is
+// already deprecated or removed there.
+type UpgradeStep struct {
+	Component string `json:"component" yaml:"component"`
+	Version   string `json:"version" yaml:"version"`
+	// Removed are the outputs that break at this version
+	Removed []*Output `json:"removed,omitempty" yaml:"removed,omitempty"`
+	// Deprecated are the outputs that become deprecated at this version
+	Deprecated []*Output `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+}
+
+// PlanUpgrade evaluates instance.Outputs at each minor version from the from version
+// to the to version of every component in to
+func (instance *Instance) PlanUpgrade(from map[string]string, to map[string]string) (*UpgradePlan, error) {
+	plan := &UpgradePlan{
+		From: from,
+		To:   to,
+	}
+	components := make([]string, 0, len(to))
+	for component := range to {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+
+	for _, component := range components {
+		if !StringInSlice(component, instance.Components) {
+			return nil, fmt.Errorf("unknown component %s - must be one of %v", component, instance.Components)
+		}
+		fromVersion, ok := from[component]
+		if !ok {
+			return nil, fmt.Errorf("you must pass a from version for every component in --to - missing component: %s", component)
+		}
+		versions, err := upgradeSteps(fromVersion, to[component])
+		if err != nil {
+			return nil, fmt.Errorf("invalid upgrade for %s: %w", component, err)
+		}
+
+		var outputs []*Output
+		for _, output := range instance.Outputs {
+			if output.APIVersion != nil && output.APIVersion.Component == component {
+				outputs = append(outputs, output)
+			}
+		}
+		deprecated := make([]bool, len(outputs))
+		removed := make([]bool, len(outputs))
+		for _, version := range versions {
+			step := UpgradeStep{
+				Component: component,
+				Version:   version,
+			}
+			targetVersions := map[string]string{component: version}
+			for i, output := range outputs {
+				state := *output
+				state.Deprecated = output.APIVersion.isDeprecatedIn(targetVersions)
+				state.Removed = output.APIVersion.isRemovedIn(targetVersions)
+				state.ReplacementAvailable = output.APIVersion.isReplacementAvailableIn(targetVersions)
+				switch {
+				case state.Removed && !removed[i]:
+					step.Removed = append(step.Removed, &state)
+				case state.Deprecated && !deprecated[i] && !instance.OnlyShowRemoved:
+					step.Deprecated = append(step.Deprecated, &state)
+				}
+				deprecated[i] = state.Deprecated
+				removed[i] = state.Removed
+			}
+			plan.Steps = append(plan.Steps, step)
+		}
+	}
+	return plan, nil
+}
+
+// upgradeSteps returns from, the first release of every minor version after it, and to.
+// from and to are compared by their normalized versions but returned as they are.
+func upgradeSteps(from string, to string) ([]string, error) {
+	normalizedFrom, ok := NormalizeVersion(from)
+	if !ok {
+		return nil, fmt.Errorf("invalid version %s", from)
+	}
+	normalizedTo, ok := NormalizeVersion(to)
+	if !ok {
+		return nil, fmt.Errorf("invalid version %s", to)
+	}
+	if semver.Compare(normalizedFrom, normalizedTo) > 0 {
+		return nil, fmt.Errorf("from version %s is newer than to version %s", from, to)
+	}
+	if semver.Major(normalizedFrom) != semver.Major(normalizedTo) {
+		return nil, fmt.Errorf("cannot plan across major versions %s and %s", from, to)
+	}
+	fromMinor, err := minorVersion(normalizedFrom)
+	if err != nil {
+		return nil, err
+	}
+	toMinor, err := minorVersion(normalizedTo)
+	if err != nil {
+		return nil, err
+	}
+	steps := []string{from}
+	for minor := fromMinor + 1; minor < toMinor; minor++ {
+		steps = append(steps, fmt.Sprintf("%s.%d.0", semver.Major(normalizedFrom), minor))
+	}
+	if semver.Compare(normalizedFrom, normalizedTo) != 0 {
+		steps = append(steps, to)
+	}
+	return steps, nil
+}
+
+func minorVersion(version string) (int, error) {
+	parts := strings.Split(semver.MajorMinor(version), ".")
+	if len(parts) < 2 {
+		return 0, nil
+	}
+	return strconv.Atoi(parts[1])
+}
+
+// DisplayUpgradePlan prints the plan based on the instance output format
+func (instance *Instance) DisplayUpgradePlan(plan *UpgradePlan) error {
+	switch instance.OutputFormat {
+	case "normal", "wide":
+		return instance.printUpgradePlanTabular(plan)
+	case "json":
+		data, err := json.Marshal(plan)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "yaml":
+		data, err := yaml.Marshal(plan)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	default:
+		return fmt.Errorf("the output format must be one of (normal|wide|json|yaml)")
+	}
+	return nil
+}
+
+func (instance *Instance) printUpgradePlanTabular(plan *UpgradePlan) error {
+	var changes int
+	for _, step := range plan.Steps {
+		changes += len(step.Removed) + len(step.Deprecated)
+	}
+	if changes == 0 {
+		fmt.Println("There were no resources found with apiVersions that are deprecated or removed during the upgrade.")
+		return nil
+	}
+
+	wide := instance.OutputFormat == "wide"
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 0, 15, 2, padChar, 0)
+	if !instance.NoHeaders {
+		headers := "COMPONENT\t TARGET\t CHANGE\t NAME\t KIND\t VERSION\t REPLACEMENT\t REPL AVAIL\t"
+		if wide {
+			headers += " NAMESPACE\t FILEPATH\t"
+		}
+		_, _ = fmt.Fprintln(w, headers)
+	}
+	for _, step := range plan.Steps {
+		for _, change := range []struct {
+			name    string
+			outputs []*Output
+		}{
+			{name: "removed", outputs: step.Removed},
+			{name: "deprecated", outputs: step.Deprecated},
+		} {
+			for _, output := range change.outputs {
+				version := output.APIVersion.Name
+				if output.Field != "" {
+					version += " " + output.Field
+				}
+				row := fmt.Sprintf("%s\t %s\t %s\t %s\t %s\t %s\t %s\t %t\t", step.Component, step.Version, change.name, output.Name, output.APIVersion.Kind, version, orNotApplicable(output.APIVersion.ReplacementAPI), output.ReplacementAvailable)
+				if wide {
+					namespace := output.Namespace
+					if namespace == "" {
+						namespace = "<UNKNOWN>"
+					}
+					filePath := output.FilePath
+					if filePath == "" {
+						filePath = "<UNKNOWN>"
+					}
+					row += fmt.Sprintf(" %s\t %s\t", namespace, filePath)
+				}
+				_, _ = fmt.Fprintln(w, row)
+			}
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,195 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testPDBOutput = &Output{
+	Name:      "web",
+	Namespace: "default",
+	FilePath:  "pdb.yaml",
+	APIVersion: &Version{
+		Name:                   "policy/v1beta1",
+		Kind:                   "PodDisruptionBudget",
+		DeprecatedIn:           "v1.21.0",
+		RemovedIn:              "v1.25.0",
+		ReplacementAPI:         "policy/v1",
+		ReplacementAvailableIn: "v1.21.0",
+		Component:              "k8s",
+	},
+}
+
+var testFlowSchemaOutput = &Output{
+	Name: "catch-all",
+	APIVersion: &Version{
+		Name:                   "flowcontrol.apiserver.k8s.io/v1beta2",
+		Kind:                   "FlowSchema",
+		DeprecatedIn:           "v1.26.0",
+		RemovedIn:              "v1.29.0",
+		ReplacementAPI:         "flowcontrol.apiserver.k8s.io/v1beta3",
+		ReplacementAvailableIn: "v1.26.0",
+		Component:              "k8s",
+	},
+}
+
+var testPlanInstance = Instance{
+	Outputs:    []*Output{testPDBOutput, testFlowSchemaOutput},
+	Components: []string{"k8s"},
+}
+
+func Test_upgradeSteps(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "several minors",
+			from: "v1.24.0",
+			to:   "v1.27.3",
+			want: []string{"v1.24.0", "v1.25.0", "v1.26.0", "v1.27.3"},
+		},
+		{
+			name: "same minor",
+			from: "v1.24.0",
+			to:   "v1.24.5",
+			want: []string{"v1.24.0", "v1.24.5"},
+		},
+		{
+			name: "same version",
+			from: "v1.24.0",
+			to:   "v1.24.0",
+			want: []string{"v1.24.0"},
+		},
+		{
+			name:    "downgrade",
+			from:    "v1.25.0",
+			to:      "v1.24.0",
+			wantErr: true,
+		},
+		{
+			name:    "major versions",
+			from:    "v1.24.0",
+			to:      "v2.0.0",
+			wantErr: true,
+		},
+		{
+			name: "vendor versions",
+			from: "1.24",
+			to:   "v1.26.5+k3s1",
+			want: []string{"1.24", "v1.25.0", "v1.26.5+k3s1"},
+		},
+		{
+			name: "same base version",
+			from: "v1.27.3-eks-2d98532",
+			to:   "v1.27.3",
+			want: []string{"v1.27.3-eks-2d98532"},
+		},
+		{
+			name:    "invalid version",
+			from:    "latest",
+			to:      "v1.25.0",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := upgradeSteps(tt.from, tt.to)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestInstance_PlanUpgrade(t *testing.T) {
+	plan, err := testPlanInstance.PlanUpgrade(map[string]string{"k8s": "v1.24.0"}, map[string]string{"k8s": "v1.29.0"})
+	assert.NoError(t, err)
+
+	type change struct {
+		version              string
+		kind                 string
+		removed              bool
+		replacementAvailable bool
+	}
+	var versions []string
+	var changes []change
+	for _, step := range plan.Steps {
+		versions = append(versions, step.Version)
+		for _, output := range step.Removed {
+			changes = append(changes, change{step.Version, output.APIVersion.Kind, true, output.ReplacementAvailable})
+		}
+		for _, output := range step.Deprecated {
+			changes = append(changes, change{step.Version, output.APIVersion.Kind, false, output.ReplacementAvailable})
+		}
+	}
+	assert.Equal(t, []string{"v1.24.0", "v1.25.0", "v1.26.0", "v1.27.0", "v1.28.0", "v1.29.0"}, versions)
+	assert.Equal(t, []change{
+		{"v1.24.0", "PodDisruptionBudget", false, true},
+		{"v1.25.0", "PodDisruptionBudget", true, true},
+		{"v1.26.0", "FlowSchema", false, true},
+		{"v1.29.0", "FlowSchema", true, true},
+	}, changes)
+
+	// the outputs themselves are left alone
+	assert.False(t, testPDBOutput.Removed)
+
+	_, err = testPlanInstance.PlanUpgrade(map[string]string{}, map[string]string{"k8s": "v1.29.0"})
+	assert.Error(t, err)
+	_, err = testPlanInstance.PlanUpgrade(map[string]string{"foo": "v1.0.0"}, map[string]string{"foo": "v1.1.0"})
+	assert.Error(t, err)
+}
+
+func ExampleInstance_DisplayUpgradePlan_normal() {
+	instance := testPlanInstance
+	instance.OutputFormat = "normal"
+	plan, _ := instance.PlanUpgrade(map[string]string{"k8s": "v1.24.0"}, map[string]string{"k8s": "v1.26.0"})
+	_ = instance.DisplayUpgradePlan(plan)
+
+	// Output:
+	// COMPONENT-- TARGET--- CHANGE------ NAME------- KIND----------------- VERSION------------------------------- REPLACEMENT--------------------------- REPL AVAIL--
+	// k8s-------- v1.24.0-- deprecated-- web-------- PodDisruptionBudget-- policy/v1beta1------------------------ policy/v1----------------------------- true--------
+	// k8s-------- v1.25.0-- removed----- web-------- PodDisruptionBudget-- policy/v1beta1------------------------ policy/v1----------------------------- true--------
+	// k8s-------- v1.26.0-- deprecated-- catch-all-- FlowSchema----------- flowcontrol.apiserver.k8s.io/v1beta2-- flowcontrol.apiserver.k8s.io/v1beta3-- true--------
+}
+
+func ExampleInstance_DisplayUpgradePlan_json() {
+	instance := testPlanInstance
+	instance.OutputFormat = "json"
+	plan, _ := instance.PlanUpgrade(map[string]string{"k8s": "v1.24.0"}, map[string]string{"k8s": "v1.25.0"})
+	_ = instance.DisplayUpgradePlan(plan)
+
+	// Output:
+	// {"from":{"k8s":"v1.24.0"},"to":{"k8s":"v1.25.0"},"steps":[{"component":"k8s","version":"v1.24.0","deprecated":[{"name":"web","filePath":"pdb.yaml","namespace":"default","api":{"version":"policy/v1beta1","kind":"PodDisruptionBudget","deprecated-in":"v1.21.0","removed-in":"v1.25.0","replacement-api":"policy/v1","replacement-available-in":"v1.21.0","component":"k8s"},"deprecated":true,"removed":false,"replacementAvailable":true}]},{"component":"k8s","version":"v1.25.0","removed":[{"name":"web","filePath":"pdb.yaml","namespace":"default","api":{"version":"policy/v1beta1","kind":"PodDisruptionBudget","deprecated-in":"v1.21.0","removed-in":"v1.25.0","replacement-api":"policy/v1","replacement-available-in":"v1.21.0","component":"k8s"},"deprecated":true,"removed":true,"replacementAvailable":true}]}]}
+}
+
+func ExampleInstance_DisplayUpgradePlan_none() {
+	instance := testPlanInstance
+	instance.OutputFormat = "normal"
+	plan, _ := instance.PlanUpgrade(map[string]string{"k8s": "v1.18.0"}, map[string]string{"k8s": "v1.19.0"})
+	_ = instance.DisplayUpgradePlan(plan)
+
+	// Output:
+	// There were no resources found with apiVersions that are deprecated or removed during the upgrade.
+}
@@ -0,0 +1,127 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// Platforms maps a platform, such as openshift or eks, and a version of that platform
+// to the target versions of the components it ships, such as
+//
+//	platforms:
+//	  openshift:
+//	    "4.14":
+//	      k8s: v1.27.0
+type Platforms map[string]map[string]map[string]string
+
+// lookup returns the target versions of a platform version. A platform version without a
+// patch version, such as 4.14, matches every patch release of it unless there is an
+// entry for that patch release.
+func (platforms Platforms) lookup(platform string, version string) (map[string]string, bool) {
+	normalized, ok := NormalizeVersion(version)
+	if !ok {
+		return nil, false
+	}
+	var minorMatch map[string]string
+	for key, targets := range platforms[platform] {
+		normalizedKey, ok := NormalizeVersion(key)
+		if !ok {
+			continue
+		}
+		if normalizedKey == normalized {
+			return targets, true
+		}
+		if strings.Count(key, ".") < 2 && semver.MajorMinor(normalizedKey) == semver.MajorMinor(normalized) {
+			minorMatch = targets
+		}
+	}
+	return minorMatch, minorMatch != nil
+}
+
+// TargetVersions expands platforms, a map of platform to platform version such as
+// openshift=4.14, into the target versions of the components they ship. It is an error
+// for an unknown platform or version, or for two platforms to target different versions
+// of the same component.
+func (platforms Platforms) TargetVersions(selected map[string]string) (map[string]string, error) {
+	names := make([]string, 0, len(selected))
+	for name := range selected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	targetVersions := map[string]string{}
+	setBy := map[string]string{}
+	for _, name := range names {
+		platform := strings.ToLower(name)
+		version := selected[name]
+		if _, found := platforms[platform]; !found {
+			return nil, fmt.Errorf("unknown platform %s - must be one of %v", name, platforms.names())
+		}
+		targets, found := platforms.lookup(platform, version)
+		if !found {
+			return nil, fmt.Errorf("unknown %s version %s - must be one of %v", platform, version, platforms.versions(platform))
+		}
+		for component, target := range targets {
+			if existing, found := targetVersions[component]; found && existing != target {
+				return nil, fmt.Errorf("%s=%s targets %s %s, but %s targets %s %s", platform, version, component, target, setBy[component], component, existing)
+			}
+			targetVersions[component] = target
+			setBy[component] = platform + "=" + version
+		}
+	}
+	return targetVersions, nil
+}
+
+func (platforms Platforms) names() []string {
+	names := make([]string, 0, len(platforms))
+	for name := range platforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (platforms Platforms) versions(platform string) []string {
+	versions := make([]string, 0, len(platforms[platform]))
+	for version := range platforms[platform] {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) < 0
+	})
+	return versions
+}
+
+// merge returns a copy of platforms with the platform versions of overrides added,
+// replacing any that are already there
+func (platforms Platforms) merge(overrides Platforms) Platforms {
+	merged := Platforms{}
+	for _, source := range []Platforms{platforms, overrides} {
+		for platform, versions := range source {
+			if merged[platform] == nil {
+				merged[platform] = map[string]map[string]string{}
+			}
+			for version, targets := range versions {
+				merged[platform][version] = targets
+			}
+		}
+	}
+	return merged
+}
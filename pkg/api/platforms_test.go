@@ -0,0 +1,116 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testPlatforms = Platforms{
+	"openshift": {
+		"4.13":   {"k8s": "v1.26.0"},
+		"4.14":   {"k8s": "v1.27.0"},
+		"4.14.2": {"k8s": "v1.27.6"},
+	},
+	"eks": {
+		"1.27": {"k8s": "v1.27.0"},
+		"1.28": {"k8s": "v1.28.0"},
+	},
+	"istio-distro": {
+		"1.0": {"istio": "v1.20.0"},
+	},
+}
+
+func TestPlatforms_TargetVersions(t *testing.T) {
+	tests := []struct {
+		name     string
+		selected map[string]string
+		want     map[string]string
+		wantErr  string
+	}{
+		{
+			name: "none",
+			want: map[string]string{},
+		},
+		{
+			name:     "minor version",
+			selected: map[string]string{"openshift": "4.14"},
+			want:     map[string]string{"k8s": "v1.27.0"},
+		},
+		{
+			name:     "patch version without an entry",
+			selected: map[string]string{"openshift": "4.14.9"},
+			want:     map[string]string{"k8s": "v1.27.0"},
+		},
+		{
+			name:     "patch version with an entry",
+			selected: map[string]string{"openshift": "v4.14.2"},
+			want:     map[string]string{"k8s": "v1.27.6"},
+		},
+		{
+			name:     "upper case platform",
+			selected: map[string]string{"EKS": "1.28"},
+			want:     map[string]string{"k8s": "v1.28.0"},
+		},
+		{
+			name:     "several platforms",
+			selected: map[string]string{"eks": "1.27", "istio-distro": "1.0", "openshift": "4.14"},
+			want:     map[string]string{"k8s": "v1.27.0", "istio": "v1.20.0"},
+		},
+		{
+			name:     "unknown platform",
+			selected: map[string]string{"aks": "1.27"},
+			wantErr:  "unknown platform aks - must be one of [eks istio-distro openshift]",
+		},
+		{
+			name:     "unknown version",
+			selected: map[string]string{"openshift": "4.15"},
+			wantErr:  "unknown openshift version 4.15 - must be one of [4.13 4.14 4.14.2]",
+		},
+		{
+			name:     "conflict",
+			selected: map[string]string{"eks": "1.28", "openshift": "4.14"},
+			wantErr:  "openshift=4.14 targets k8s v1.27.0, but eks=1.28 targets k8s v1.28.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := testPlatforms.TargetVersions(tt.selected)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlatforms_merge(t *testing.T) {
+	got := testPlatforms.merge(Platforms{
+		"openshift": {"4.14": {"k8s": "v1.27.1"}, "4.15": {"k8s": "v1.28.0"}},
+		"gke":       {"1.28": {"k8s": "v1.28.0"}},
+	})
+	assert.Equal(t, map[string]string{"k8s": "v1.26.0"}, got["openshift"]["4.13"])
+	assert.Equal(t, map[string]string{"k8s": "v1.27.1"}, got["openshift"]["4.14"])
+	assert.Equal(t, map[string]string{"k8s": "v1.28.0"}, got["openshift"]["4.15"])
+	assert.Equal(t, map[string]string{"k8s": "v1.28.0"}, got["gke"]["1.28"])
+
+	// the platforms merged into are not modified
+	assert.Len(t, testPlatforms["openshift"], 3)
+	assert.NotContains(t, testPlatforms, "gke")
+}
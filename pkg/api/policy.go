@@ -0,0 +1,336 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+	"gopkg.in/yaml.v3"
+)
+
+// Severity is how serious a finding is
+type Severity string
+
+const (
+	SeverityError Severity = "error"
+	SeverityWarn  Severity = "warn"
+	SeverityInfo  Severity = "info"
+)
+
+// severityRanks orders the severities, a higher rank is more serious
+var severityRanks = map[Severity]int{
+	SeverityInfo:  1,
+	SeverityWarn:  2,
+	SeverityError: 3,
+}
+
+// defaultExitCodes are the exit codes of the severities of rules without an exit code
+var defaultExitCodes = map[Severity]int{
+	SeverityInfo:  0,
+	SeverityWarn:  2,
+	SeverityError: 3,
+}
+
+// FailOnConditions are the conditions --fail-on accepts
+var FailOnConditions = []string{"deprecated", "removed", "replacement-unavailable", "none"}
+
+// Policy maps findings to a severity and an exit code. Every finding gets the severity and
+// exit code of the first rule it matches, or info and 0 if it matches none. The return code
+// is the highest exit code of the findings with the highest severity.
+type Policy struct {
+	Rules []PolicyRule `json:"rules" yaml:"rules"`
+}
+
+// PolicyRule is a condition and the severity and exit code of the findings that match it
+type PolicyRule struct {
+	// Name describes the rule
+	Name  string      `json:"name,omitempty" yaml:"name,omitempty"`
+	Match PolicyMatch `json:"match" yaml:"match"`
+	// Severity is one of error, warn or info
+	Severity Severity `json:"severity" yaml:"severity"`
+	// ExitCode defaults to 3 for error, 2 for warn and 0 for info
+	ExitCode *int `json:"exitCode,omitempty" yaml:"exitCode,omitempty"`
+}
+
+// PolicyMatch is the condition of a rule. Every condition that is set must match, and
+// a list matches if any of its items do.
+type PolicyMatch struct {
+	Components []string `json:"components,omitempty" yaml:"components,omitempty"`
+	Kinds      []string `json:"kinds,omitempty" yaml:"kinds,omitempty"`
+	// Namespaces are globs such as team-*, see path.Match
+	Namespaces             []string `json:"namespaces,omitempty" yaml:"namespaces,omitempty"`
+	Deprecated             *bool    `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+	Removed                *bool    `json:"removed,omitempty" yaml:"removed,omitempty"`
+	ReplacementUnavailable *bool    `json:"replacementUnavailable,omitempty" yaml:"replacementUnavailable,omitempty"`
+	// RemovedWithinMinors matches versions that are removed in the target version or at most
+	// this many minor versions after it
+	RemovedWithinMinors *int `json:"removedWithinMinors,omitempty" yaml:"removedWithinMinors,omitempty"`
+}
+
+// finding is an output along with its state in the target versions
+type finding struct {
+	output                 *Output
+	deprecated             bool
+	removed                bool
+	replacementUnavailable bool
+	targetVersions         map[string]string
+}
+
+func newFinding(output *Output, targetVersions map[string]string) finding {
+	return finding{
+		output:                 output,
+		deprecated:             output.APIVersion.isDeprecatedIn(targetVersions),
+		removed:                output.APIVersion.isRemovedIn(targetVersions),
+		replacementUnavailable: !output.APIVersion.isReplacementAvailableIn(targetVersions),
+		targetVersions:         targetVersions,
+	}
+}
+
+// LoadPolicy reads a policy file
+func LoadPolicy(file string) (*Policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	policy := &Policy{}
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(policy); err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err.Error())
+	}
+	if err := policy.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err.Error())
+	}
+	return policy, nil
+}
+
+func (policy *Policy) validate() error {
+	if len(policy.Rules) == 0 {
+		return fmt.Errorf("a policy must have at least one rule")
+	}
+	for i, rule := range policy.Rules {
+		name := rule.Name
+		if name == "" {
+			name = strconv.Itoa(i + 1)
+		}
+		if _, found := severityRanks[rule.Severity]; !found {
+			return fmt.Errorf("rule %s: invalid severity %q, must be one of error, warn or info", name, rule.Severity)
+		}
+		if rule.ExitCode != nil && (*rule.ExitCode < 0 || *rule.ExitCode > 255) {
+			return fmt.Errorf("rule %s: exit code %d must be between 0 and 255", name, *rule.ExitCode)
+		}
+		for _, namespace := range rule.Match.Namespaces {
+			if _, err := path.Match(namespace, ""); err != nil {
+				return fmt.Errorf("rule %s: invalid namespace glob %q", name, namespace)
+			}
+		}
+		if rule.Match.RemovedWithinMinors != nil && *rule.Match.RemovedWithinMinors < 0 {
+			return fmt.Errorf("rule %s: removedWithinMinors must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// FailOnPolicy returns the policy of --fail-on, failing with the exit code of the default
+// policy on each of conditions and passing on anything else
+func FailOnPolicy(conditions []string) (*Policy, error) {
+	for _, condition := range conditions {
+		if !StringInSlice(condition, FailOnConditions) {
+			return nil, fmt.Errorf("invalid --fail-on %s, must be one of %v", condition, FailOnConditions)
+		}
+	}
+	// removals come first and are the only errors, so that an unavailable replacement
+	// never masks a removal, see defaultPolicy
+	policy := &Policy{Rules: []PolicyRule{}}
+	if StringInSlice("removed", conditions) {
+		policy.Rules = append(policy.Rules, PolicyRule{Name: "removed", Match: PolicyMatch{Removed: boolPtr(true)}, Severity: SeverityError, ExitCode: intPtr(3)})
+	}
+	if StringInSlice("replacement-unavailable", conditions) {
+		policy.Rules = append(policy.Rules, PolicyRule{Name: "replacement-unavailable", Match: PolicyMatch{ReplacementUnavailable: boolPtr(true)}, Severity: SeverityWarn, ExitCode: intPtr(4)})
+	}
+	if StringInSlice("deprecated", conditions) {
+		policy.Rules = append(policy.Rules, PolicyRule{Name: "deprecated", Match: PolicyMatch{Deprecated: boolPtr(true)}, Severity: SeverityWarn, ExitCode: intPtr(2)})
+	}
+	return policy, nil
+}
+
+// defaultPolicy is the policy without --policy or --fail-on: exit 3 when a version is
+// removed, 4 when a replacement is unavailable and 2 when a version is deprecated, each
+// unless it is ignored with the flags of the instance. Removals are errors and the others
+// are warnings, so a removal decides the exit code even if a replacement is unavailable.
+func (instance *Instance) defaultPolicy() *Policy {
+	policy := &Policy{}
+	if !instance.IgnoreRemovals {
+		policy.Rules = append(policy.Rules, PolicyRule{Match: PolicyMatch{Removed: boolPtr(true)}, Severity: SeverityError, ExitCode: intPtr(3)})
+	}
+	if !instance.IgnoreUnavailableReplacements {
+		policy.Rules = append(policy.Rules, PolicyRule{Match: PolicyMatch{ReplacementUnavailable: boolPtr(true)}, Severity: SeverityWarn, ExitCode: intPtr(4)})
+	}
+	if !instance.IgnoreDeprecations {
+		deprecated := PolicyRule{Match: PolicyMatch{Deprecated: boolPtr(true)}, Severity: SeverityWarn, ExitCode: intPtr(2)}
+		if instance.IgnoreUnavailableReplacements {
+			deprecated.Match.ReplacementUnavailable = boolPtr(false)
+		}
+		policy.Rules = append(policy.Rules, deprecated)
+	}
+	return policy
+}
+
+// evaluate returns the severity and exit code of a finding
+func (policy *Policy) evaluate(f finding) (Severity, int) {
+	for _, rule := range policy.Rules {
+		if !rule.Match.matches(f) {
+			continue
+		}
+		if rule.ExitCode != nil {
+			return rule.Severity, *rule.ExitCode
+		}
+		return rule.Severity, defaultExitCodes[rule.Severity]
+	}
+	return SeverityInfo, 0
+}
+
+// outcome returns the severity and exit code of an output, and false if it is not a finding.
+// Suppressed outputs and outputs that are neither deprecated nor removed are not findings.
+// Outputs of rules keep the severity of the rule and its default exit code.
+func (policy *Policy) outcome(output *Output, targetVersions map[string]string) (Severity, int, bool) {
+	if output.Suppressed || output.APIVersion == nil {
+		return "", 0, false
+	}
+	if output.Rule != "" {
+		return output.Severity, defaultExitCodes[output.Severity], true
+	}
+	f := newFinding(output, targetVersions)
+	if !f.deprecated && !f.removed {
+		return "", 0, false
+	}
+	severity, exitCode := policy.evaluate(f)
+	return severity, exitCode, true
+}
+
+// returnCode returns the highest exit code of the findings with the highest severity, see outcome
+func (policy *Policy) returnCode(outputs []*Output, targetVersions map[string]string) int {
+	var maxSeverity Severity
+	returnCode := 0
+	for _, output := range outputs {
+		severity, exitCode, ok := policy.outcome(output, targetVersions)
+		if !ok {
+			continue
+		}
+		switch {
+		case severityRanks[severity] > severityRanks[maxSeverity]:
+			maxSeverity = severity
+			returnCode = exitCode
+		case severity == maxSeverity && exitCode > returnCode:
+			returnCode = exitCode
+		}
+	}
+	return returnCode
+}
+
+// policy returns the policy of the instance, or the default policy if it has none
+func (instance *Instance) policy() *Policy {
+	if instance.Policy != nil {
+		return instance.Policy
+	}
+	return instance.defaultPolicy()
+}
+
+// applyPolicy sets the severity of the outputs from the policy of the instance, if it has one.
+// Outputs of rules keep the severity of the rule.
+func (instance *Instance) applyPolicy() {
+	if instance.Policy == nil {
+		return
+	}
+	for _, output := range instance.Outputs {
+		if output.APIVersion == nil || output.Rule != "" {
+			continue
+		}
+		output.Severity, _ = instance.Policy.evaluate(newFinding(output, instance.TargetVersions))
+	}
+}
+
+func (match PolicyMatch) matches(f finding) bool {
+	version := f.output.APIVersion
+	if len(match.Components) > 0 && !StringInSlice(version.Component, match.Components) {
+		return false
+	}
+	if len(match.Kinds) > 0 && !StringInSlice(version.Kind, match.Kinds) {
+		return false
+	}
+	if len(match.Namespaces) > 0 && !matchesAnyGlob(f.output.Namespace, match.Namespaces) {
+		return false
+	}
+	if match.Deprecated != nil && *match.Deprecated != f.deprecated {
+		return false
+	}
+	if match.Removed != nil && *match.Removed != f.removed {
+		return false
+	}
+	if match.ReplacementUnavailable != nil && *match.ReplacementUnavailable != f.replacementUnavailable {
+		return false
+	}
+	if match.RemovedWithinMinors != nil && !removedWithinMinors(version, f.targetVersions[version.Component], *match.RemovedWithinMinors) {
+		return false
+	}
+	return true
+}
+
+func matchesAnyGlob(value string, globs []string) bool {
+	for _, glob := range globs {
+		if matched, _ := path.Match(glob, value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// removedWithinMinors returns whether version is removed in target or at most minors minor
+// versions after it, in the same major version
+func removedWithinMinors(version *Version, target string, minors int) bool {
+	if version.RemovedIn == "" {
+		return false
+	}
+	removedIn, ok := NormalizeVersion(version.RemovedIn)
+	if !ok {
+		return false
+	}
+	normalizedTarget, ok := NormalizeVersion(target)
+	if !ok {
+		return false
+	}
+	if semver.Compare(removedIn, normalizedTarget) <= 0 {
+		return true
+	}
+	if semver.Major(removedIn) != semver.Major(normalizedTarget) {
+		return false
+	}
+	removedMinor, _ := minorVersion(removedIn)
+	targetMinor, _ := minorVersion(normalizedTarget)
+	return removedMinor-targetMinor <= minors
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func intPtr(i int) *int {
+	return &i
+}
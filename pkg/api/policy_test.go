@@ -0,0 +1,194 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	policyDeprecated       = &Output{Name: "deprecated", Namespace: "team-a", APIVersion: &Version{Kind: "PodSecurityPolicy", DeprecatedIn: "v1.21.0", RemovedIn: "v1.25.0", ReplacementAvailableIn: "v1.21.0", Component: "k8s"}}
+	policyRemoved          = &Output{Name: "removed", Namespace: "team-b", APIVersion: &Version{Kind: "Ingress", DeprecatedIn: "v1.14.0", RemovedIn: "v1.22.0", ReplacementAvailableIn: "v1.19.0", Component: "k8s"}}
+	policyNoReplace        = &Output{Name: "no-replacement", Namespace: "kube-system", APIVersion: &Version{Kind: "Gateway", DeprecatedIn: "v1.0.0", Component: "istio"}}
+	policyRemovedNoReplace = &Output{Name: "removed-no-replacement", Namespace: "team-c", APIVersion: &Version{Kind: "Gateway", DeprecatedIn: "v1.0.0", RemovedIn: "v1.10.0", Component: "istio"}}
+	policyTargets          = map[string]string{"k8s": "v1.23.0", "istio": "v1.20.0"}
+)
+
+func TestPolicy_returnCode(t *testing.T) {
+	policy := &Policy{Rules: []PolicyRule{
+		{Name: "system namespaces", Match: PolicyMatch{Namespaces: []string{"kube-*"}}, Severity: SeverityInfo},
+		{Name: "removed soon", Match: PolicyMatch{Components: []string{"k8s"}, RemovedWithinMinors: intPtr(2)}, Severity: SeverityError, ExitCode: intPtr(10)},
+		{Name: "removed", Match: PolicyMatch{Removed: boolPtr(true)}, Severity: SeverityError},
+		{Name: "deprecated", Match: PolicyMatch{Deprecated: boolPtr(true)}, Severity: SeverityWarn, ExitCode: intPtr(20)},
+	}}
+	tests := []struct {
+		name    string
+		outputs []*Output
+		want    int
+	}{
+		{name: "no findings", want: 0},
+		{name: "info only", outputs: []*Output{policyNoReplace}, want: 0},
+		{name: "removed within two minors", outputs: []*Output{policyDeprecated}, want: 10},
+		{name: "removed", outputs: []*Output{policyRemoved}, want: 10},
+		{name: "info does not lower the code", outputs: []*Output{policyNoReplace, policyRemoved}, want: 10},
+		{name: "suppressed", outputs: []*Output{{Suppressed: true, APIVersion: policyRemoved.APIVersion}}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, policy.returnCode(tt.outputs, policyTargets))
+		})
+	}
+
+	warnOnly := &Policy{Rules: []PolicyRule{
+		{Match: PolicyMatch{Removed: boolPtr(true)}, Severity: SeverityError},
+		{Match: PolicyMatch{Deprecated: boolPtr(true)}, Severity: SeverityWarn, ExitCode: intPtr(20)},
+	}}
+	assert.Equal(t, 3, warnOnly.returnCode([]*Output{policyDeprecated, policyRemoved}, policyTargets))
+	assert.Equal(t, 3, warnOnly.returnCode([]*Output{policyRemoved, policyDeprecated}, policyTargets))
+	assert.Equal(t, 20, warnOnly.returnCode([]*Output{policyDeprecated}, policyTargets))
+}
+
+func TestInstance_defaultPolicy(t *testing.T) {
+	outputs := []*Output{policyDeprecated, policyRemoved, policyNoReplace}
+	tests := []struct {
+		name     string
+		instance Instance
+		outputs  []*Output
+		want     int
+	}{
+		{name: "removal ranks above an unavailable replacement", outputs: outputs, want: 3},
+		{name: "removed and replacement unavailable", outputs: []*Output{policyRemovedNoReplace}, want: 3},
+		{name: "unavailable replacement", outputs: []*Output{policyDeprecated, policyNoReplace}, want: 4},
+		{name: "removals ignored with an unavailable replacement", instance: Instance{IgnoreRemovals: true}, outputs: outputs, want: 4},
+		{name: "unavailable replacement ignored", instance: Instance{IgnoreUnavailableReplacements: true}, outputs: outputs, want: 3},
+		{name: "removals ignored", instance: Instance{IgnoreUnavailableReplacements: true, IgnoreRemovals: true}, outputs: outputs, want: 2},
+		{name: "everything ignored", instance: Instance{IgnoreUnavailableReplacements: true, IgnoreRemovals: true, IgnoreDeprecations: true}, outputs: outputs, want: 0},
+		{name: "deprecated", outputs: []*Output{policyDeprecated}, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.instance.Outputs = tt.outputs
+			tt.instance.TargetVersions = policyTargets
+			assert.Equal(t, tt.want, tt.instance.GetReturnCode())
+		})
+	}
+}
+
+func TestFailOnPolicy(t *testing.T) {
+	policy, err := FailOnPolicy([]string{"deprecated", "removed"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"removed", "deprecated"}, []string{policy.Rules[0].Name, policy.Rules[1].Name})
+	assert.Equal(t, 3, policy.returnCode([]*Output{policyDeprecated, policyRemoved, policyNoReplace}, policyTargets))
+
+	policy, err = FailOnPolicy([]string{"replacement-unavailable", "removed"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"removed", "replacement-unavailable"}, []string{policy.Rules[0].Name, policy.Rules[1].Name})
+	assert.Equal(t, 3, policy.returnCode([]*Output{policyNoReplace, policyRemovedNoReplace}, policyTargets))
+	assert.Equal(t, 4, policy.returnCode([]*Output{policyDeprecated, policyNoReplace}, policyTargets))
+
+	policy, err = FailOnPolicy([]string{"removed"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, policy.returnCode([]*Output{policyDeprecated, policyNoReplace}, policyTargets))
+
+	policy, err = FailOnPolicy([]string{"none"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, policy.returnCode([]*Output{policyRemoved}, policyTargets))
+
+	_, err = FailOnPolicy([]string{"removals"})
+	assert.EqualError(t, err, "invalid --fail-on removals, must be one of [deprecated removed replacement-unavailable none]")
+}
+
+func TestLoadPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			data: `rules:
+- name: removed soon
+  match:
+    components: [k8s]
+    namespaces: ["team-*"]
+    removedWithinMinors: 1
+  severity: error
+  exitCode: 5
+- match:
+    deprecated: true
+  severity: warn
+`,
+		},
+		{
+			name:    "no rules",
+			data:    "rules: []\n",
+			wantErr: "a policy must have at least one rule",
+		},
+		{
+			name: "invalid severity",
+			data: `rules:
+- match:
+    removed: true
+  severity: fatal
+`,
+			wantErr: `rule 1: invalid severity "fatal", must be one of error, warn or info`,
+		},
+		{
+			name: "invalid glob",
+			data: `rules:
+- name: teams
+  match:
+    namespaces: ["team-["]
+  severity: info
+`,
+			wantErr: `rule teams: invalid namespace glob "team-["`,
+		},
+		{
+			name: "unknown key",
+			data: `rules:
+- match:
+    namespace: team-a
+  severity: info
+`,
+			wantErr: "yaml: unmarshal errors:\n  line 3: field namespace not found in type api.PolicyMatch",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "policy.yaml")
+			assert.NoError(t, os.WriteFile(file, []byte(tt.data), 0644))
+			_, err := LoadPolicy(file)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, file+": "+tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_removedWithinMinors(t *testing.T) {
+	version := &Version{RemovedIn: "v1.25.0"}
+	assert.True(t, removedWithinMinors(version, "v1.26.0", 0))
+	assert.True(t, removedWithinMinors(version, "v1.25.0", 0))
+	assert.True(t, removedWithinMinors(version, "1.23", 2))
+	assert.False(t, removedWithinMinors(version, "v1.22.0", 2))
+	assert.False(t, removedWithinMinors(&Version{}, "v1.22.0", 2))
+	assert.False(t, removedWithinMinors(&Version{RemovedIn: "v2.0.0"}, "v1.29.0", 10))
+}
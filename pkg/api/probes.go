@@ -0,0 +1,66 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"strings"
+)
+
+// ComponentProbe finds the version of a component running in a cluster from the image tag
+// of a container of the Deployments matching a label selector, such as
+//
+//	component-probes:
+//	  istio:
+//	  - selector: app=istiod
+//	    container: discovery
+type ComponentProbe struct {
+	// Namespace is the namespace of the Deployments. If it is empty, all namespaces are searched.
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	// Selector is a label selector matching the Deployments
+	Selector string `json:"selector" yaml:"selector"`
+	// Container is the name of the container to read the image tag of. If it is empty, the
+	// first container is used.
+	Container string `json:"container,omitempty" yaml:"container,omitempty"`
+}
+
+// ComponentProbes maps a component to the probes that find its version. The probes of
+// a component are tried in order until one finds a version.
+type ComponentProbes map[string][]ComponentProbe
+
+// merge returns a copy of probes with the probes of the components in overrides replacing
+// any that are already there
+func (probes ComponentProbes) merge(overrides ComponentProbes) ComponentProbes {
+	merged := ComponentProbes{}
+	for _, source := range []ComponentProbes{probes, overrides} {
+		for component, componentProbes := range source {
+			merged[component] = componentProbes
+		}
+	}
+	return merged
+}
+
+// ImageTagVersion returns the normalized version of the tag of a container image, such as
+// v1.20.1 for docker.io/istio/pilot:1.20.1-distroless. ok is false if the image has no tag
+// or the tag is not a version.
+func ImageTagVersion(image string) (version string, ok bool) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return "", false
+	}
+	return NormalizeVersion(image[i+1:])
+}
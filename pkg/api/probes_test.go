@@ -0,0 +1,61 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageTagVersion(t *testing.T) {
+	tests := []struct {
+		image  string
+		want   string
+		wantOK bool
+	}{
+		{image: "docker.io/istio/pilot:1.20.1", want: "v1.20.1", wantOK: true},
+		{image: "docker.io/istio/pilot:1.20.1-distroless", want: "v1.20.1", wantOK: true},
+		{image: "quay.io/jetstack/cert-manager-controller:v1.13.2", want: "v1.13.2", wantOK: true},
+		{image: "registry.example.com:5000/cert-manager-controller:v1.13.2", want: "v1.13.2", wantOK: true},
+		{image: "quay.io/jetstack/cert-manager-controller:v1.13.2@sha256:0123abcd", want: "v1.13.2", wantOK: true},
+		{image: "registry.example.com:5000/cert-manager-controller", wantOK: false},
+		{image: "quay.io/jetstack/cert-manager-controller@sha256:0123abcd", wantOK: false},
+		{image: "istio/pilot:latest", wantOK: false},
+		{image: "istio/pilot", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			got, ok := ImageTagVersion(tt.image)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestComponentProbes_merge(t *testing.T) {
+	defaults := ComponentProbes{
+		"istio":        {{Selector: "app=istiod", Container: "discovery"}},
+		"cert-manager": {{Selector: "app=cert-manager"}},
+	}
+	got := defaults.merge(ComponentProbes{
+		"istio": {{Namespace: "mesh", Selector: "app=pilot"}},
+	})
+	assert.Equal(t, ComponentProbes{
+		"istio":        {{Namespace: "mesh", Selector: "app=pilot"}},
+		"cert-manager": {{Selector: "app=cert-manager"}},
+	}, got)
+	assert.Equal(t, "app=istiod", defaults["istio"][0].Selector)
+}
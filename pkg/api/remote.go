@@ -0,0 +1,287 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// SignatureSuffix is appended to the URL of a versions file to get the URL of its detached signature
+const SignatureSuffix = ".sig"
+
+// VersionFetcher downloads versions files from http(s) URLs. Downloads are cached and
+// revalidated with ETag and If-Modified-Since. If the server cannot be reached, the last
+// good copy in the cache is used instead.
+type VersionFetcher struct {
+	// CacheDir is the directory downloads are cached in. If blank, it is lamb/versions
+	// under the user cache directory.
+	CacheDir string
+	// PublicKey is the key versions files must be signed with. If set, the detached signature
+	// at the URL with SignatureSuffix appended must verify, or the versions file is rejected.
+	PublicKey ed25519.PublicKey
+	// Client is the client used for downloads. If nil, a client with a 30 second timeout is used.
+	Client *http.Client
+}
+
+// cacheMeta is the validators of a cached versions file
+type cacheMeta struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last-modified,omitempty"`
+}
+
+// errUnreachable is a failure that the cached copy can stand in for
+var errUnreachable = errors.New("versions server unreachable")
+
+// IsURL returns whether path is an http(s) URL rather than a file
+func IsURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// ParsePublicKey parses an ed25519 public key, either PEM encoded as written by cosign and
+// openssl, or as the base64 encoded 32 bytes of the key
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse public key: %s", err.Error())
+		}
+		publicKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("public key must be an ed25519 key, got %T", key)
+		}
+		return publicKey, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be PEM encoded or %d base64 encoded bytes", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// Fetch returns the versions file at url
+func (fetcher *VersionFetcher) Fetch(url string) ([]byte, error) {
+	cacheDir, err := fetcher.cacheDir()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(url))
+	cachePath := filepath.Join(cacheDir, hex.EncodeToString(sum[:]))
+
+	data, err := fetcher.download(url, cachePath)
+	if err == nil {
+		return data, nil
+	}
+	if !errors.Is(err, errUnreachable) {
+		return nil, err
+	}
+	cached, cacheErr := fetcher.readCache(cachePath)
+	if cacheErr != nil {
+		return nil, fmt.Errorf("could not fetch %s and there is no usable cached copy: %s (%s)", url, err.Error(), cacheErr.Error())
+	}
+	klog.Warningf("could not fetch %s, using the cached copy: %s", url, err.Error())
+	return cached, nil
+}
+
+// download fetches url, revalidating the cached copy if there is one, and updates the cache
+func (fetcher *VersionFetcher) download(url string, cachePath string) ([]byte, error) {
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	meta := cacheMeta{}
+	if data, err := os.ReadFile(cachePath + ".json"); err == nil && json.Unmarshal(data, &meta) == nil && fetcher.canRevalidate(cachePath) {
+		if meta.ETag != "" {
+			request.Header.Set("If-None-Match", meta.ETag)
+		}
+		if meta.LastModified != "" {
+			request.Header.Set("If-Modified-Since", meta.LastModified)
+		}
+	}
+	response, err := fetcher.client().Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errUnreachable, err.Error())
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusNotModified:
+		klog.V(2).Infof("cached copy of %s is up to date", url)
+		return fetcher.readCache(cachePath)
+	case response.StatusCode >= 500:
+		return nil, fmt.Errorf("%w: %s returned %s", errUnreachable, url, response.Status)
+	case response.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("could not fetch %s: %s", url, response.Status)
+	}
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errUnreachable, err.Error())
+	}
+
+	var signature []byte
+	if fetcher.PublicKey != nil {
+		signature, err = fetcher.fetchSignature(url + SignatureSuffix)
+		if err != nil {
+			return nil, err
+		}
+		err = fetcher.verify(data, signature)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", url, err.Error())
+		}
+	}
+	klog.V(2).Infof("fetched %s", url)
+
+	meta = cacheMeta{
+		URL:          url,
+		ETag:         response.Header.Get("ETag"),
+		LastModified: response.Header.Get("Last-Modified"),
+	}
+	err = writeCache(cachePath, data, signature, meta)
+	if err != nil {
+		klog.Warningf("could not cache %s: %s", url, err.Error())
+	}
+	return data, nil
+}
+
+// canRevalidate returns false if a public key is set but the cached copy has no signature,
+// such as a copy cached before the key was configured
+func (fetcher *VersionFetcher) canRevalidate(cachePath string) bool {
+	if fetcher.PublicKey == nil {
+		return true
+	}
+	_, err := os.Stat(cachePath + SignatureSuffix)
+	return err == nil
+}
+
+func (fetcher *VersionFetcher) fetchSignature(url string) ([]byte, error) {
+	response, err := fetcher.client().Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errUnreachable, err.Error())
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not fetch signature %s: %s", url, response.Status)
+	}
+	return io.ReadAll(response.Body)
+}
+
+// verify checks a detached signature of data, either the raw signature or base64 encoded as written by cosign
+func (fetcher *VersionFetcher) verify(data []byte, signature []byte) error {
+	if len(signature) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+		if err != nil {
+			return fmt.Errorf("invalid signature: %s", err.Error())
+		}
+		signature = decoded
+	}
+	if !ed25519.Verify(fetcher.PublicKey, data, signature) {
+		return errors.New("signature does not match the public key")
+	}
+	return nil
+}
+
+// readCache returns the cached copy, verifying it again if a public key is set
+func (fetcher *VersionFetcher) readCache(cachePath string) ([]byte, error) {
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		return nil, err
+	}
+	if fetcher.PublicKey != nil {
+		signature, err := os.ReadFile(cachePath + SignatureSuffix)
+		if err != nil {
+			return nil, fmt.Errorf("cached copy is not signed: %s", err.Error())
+		}
+		err = fetcher.verify(data, signature)
+		if err != nil {
+			return nil, fmt.Errorf("cached copy: %s", err.Error())
+		}
+	}
+	return data, nil
+}
+
+// writeCache writes the data, signature and validators of a download. The data is written
+// last so that an interrupted write never leaves a copy with the wrong signature behind.
+func writeCache(cachePath string, data []byte, signature []byte, meta cacheMeta) error {
+	err := os.MkdirAll(filepath.Dir(cachePath), 0755)
+	if err != nil {
+		return err
+	}
+	metaData, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	if signature != nil {
+		err = writeFileAtomic(cachePath+SignatureSuffix, signature)
+		if err != nil {
+			return err
+		}
+	}
+	err = writeFileAtomic(cachePath+".json", metaData)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(cachePath, data)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (fetcher *VersionFetcher) cacheDir() (string, error) {
+	if fetcher.CacheDir != "" {
+		return fetcher.CacheDir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("could not find the user cache directory, set a cache directory instead: %s", err.Error())
+	}
+	return filepath.Join(dir, "lamb", "versions"), nil
+}
+
+func (fetcher *VersionFetcher) client() *http.Client {
+	if fetcher.Client != nil {
+		return fetcher.Client
+	}
+	return &http.Client{Timeout: 30 * time.Second}
+}
@@ -0,0 +1,199 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var remoteVersions = []byte(`deprecated-versions:
+- version: internal.example.com/v1alpha1
+  kind: Widget
+  removed-in: v1.2.0
+  component: internal
+target-versions:
+  internal: v1.2.0
+`)
+
+// versionsServer serves a versions file and its signature, and records the requests it gets
+type versionsServer struct {
+	sync.Mutex
+	data      []byte
+	signature []byte
+	status    int
+	requests  []*http.Request
+}
+
+func (s *versionsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	s.requests = append(s.requests, r)
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+		return
+	}
+	switch r.URL.Path {
+	case "/versions.yaml":
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write(s.data)
+	case "/versions.yaml.sig":
+		if s.signature == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(s.signature)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *versionsServer) lastRequest() *http.Request {
+	s.Lock()
+	defer s.Unlock()
+	return s.requests[len(s.requests)-1]
+}
+
+func TestVersionFetcher_Fetch(t *testing.T) {
+	handler := &versionsServer{data: remoteVersions}
+	server := httptest.NewServer(handler)
+	url := server.URL + "/versions.yaml"
+	fetcher := &VersionFetcher{CacheDir: t.TempDir()}
+
+	got, err := fetcher.Fetch(url)
+	assert.NoError(t, err)
+	assert.Equal(t, remoteVersions, got)
+	assert.Equal(t, "", handler.lastRequest().Header.Get("If-None-Match"))
+
+	// revalidated with the validators of the cached copy
+	got, err = fetcher.Fetch(url)
+	assert.NoError(t, err)
+	assert.Equal(t, remoteVersions, got)
+	assert.Equal(t, `"v1"`, handler.lastRequest().Header.Get("If-None-Match"))
+	assert.Equal(t, "Mon, 02 Jan 2006 15:04:05 GMT", handler.lastRequest().Header.Get("If-Modified-Since"))
+
+	// server errors fall back to the cached copy
+	handler.Lock()
+	handler.status = http.StatusServiceUnavailable
+	handler.Unlock()
+	got, err = fetcher.Fetch(url)
+	assert.NoError(t, err)
+	assert.Equal(t, remoteVersions, got)
+
+	// but a missing file does not
+	handler.Lock()
+	handler.status = http.StatusNotFound
+	handler.Unlock()
+	_, err = fetcher.Fetch(url)
+	assert.EqualError(t, err, "could not fetch "+url+": 404 Not Found")
+
+	// a server that cannot be reached falls back to the cached copy, if there is one
+	server.Close()
+	got, err = fetcher.Fetch(url)
+	assert.NoError(t, err)
+	assert.Equal(t, remoteVersions, got)
+	_, err = (&VersionFetcher{CacheDir: t.TempDir()}).Fetch(url)
+	assert.ErrorContains(t, err, "could not fetch "+url+" and there is no usable cached copy")
+}
+
+func TestVersionFetcher_Fetch_signed(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	signature := []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, remoteVersions)))
+
+	handler := &versionsServer{data: remoteVersions, signature: signature}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	url := server.URL + "/versions.yaml"
+	fetcher := &VersionFetcher{CacheDir: t.TempDir(), PublicKey: publicKey}
+
+	got, err := fetcher.Fetch(url)
+	assert.NoError(t, err)
+	assert.Equal(t, remoteVersions, got)
+
+	// the cached copy is verified again
+	got, err = fetcher.Fetch(url)
+	assert.NoError(t, err)
+	assert.Equal(t, remoteVersions, got)
+
+	// a tampered file fails closed rather than falling back to the cache
+	handler.Lock()
+	handler.data = append([]byte("# tampered\n"), remoteVersions...)
+	handler.Unlock()
+	tampered := &VersionFetcher{CacheDir: t.TempDir(), PublicKey: publicKey}
+	_, err = tampered.Fetch(url)
+	assert.EqualError(t, err, url+": signature does not match the public key")
+
+	// as does a missing signature
+	handler.Lock()
+	handler.data = remoteVersions
+	handler.signature = nil
+	handler.Unlock()
+	_, err = tampered.Fetch(url)
+	assert.EqualError(t, err, "could not fetch signature "+url+".sig: 404 Not Found")
+
+	// a copy cached without a signature is not trusted once a key is set
+	unsigned := &VersionFetcher{CacheDir: t.TempDir()}
+	_, err = unsigned.Fetch(url)
+	assert.NoError(t, err)
+	unsigned.PublicKey = publicKey
+	_, err = unsigned.Fetch(url)
+	assert.Equal(t, "", handler.lastRequest().Header.Get("If-None-Match"))
+	assert.EqualError(t, err, "could not fetch signature "+url+".sig: 404 Not Found")
+}
+
+func TestParsePublicKey(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	assert.NoError(t, err)
+
+	got, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	assert.NoError(t, err)
+	assert.Equal(t, publicKey, got)
+
+	got, err = ParsePublicKey([]byte(base64.StdEncoding.EncodeToString(publicKey) + "\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, publicKey, got)
+
+	_, err = ParsePublicKey([]byte("not a key"))
+	assert.EqualError(t, err, "public key must be PEM encoded or 32 base64 encoded bytes")
+}
+
+func TestMergeVersionFiles_url(t *testing.T) {
+	server := httptest.NewServer(&versionsServer{data: remoteVersions})
+	defer server.Close()
+	url := server.URL + "/versions.yaml"
+
+	got, err := MergeVersionFiles([]string{url}, VersionFile{}, true, &VersionFetcher{CacheDir: t.TempDir()})
+	assert.NoError(t, err)
+	assert.Equal(t, []Version{
+		{Name: "internal.example.com/v1alpha1", Kind: "Widget", RemovedIn: "v1.2.0", Component: "internal", Origin: url},
+	}, got.DeprecatedVersions)
+	assert.Equal(t, map[string]string{"internal": "v1.2.0"}, got.TargetVersions)
+}
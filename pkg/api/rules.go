@@ -0,0 +1,167 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"gopkg.in/yaml.v3"
+	"k8s.io/klog/v2"
+)
+
+// Rules are user defined checks of whole manifests. Every manifest that a rule expression
+// is true for becomes an output with the message and severity of the rule.
+type Rules struct {
+	Rules []Rule `json:"rules" yaml:"rules"`
+}
+
+// Rule is a CEL expression evaluated against every manifest, which is the variable object,
+// such as object.kind == "Ingress" && "kubernetes.io/ingress.class" in object.metadata.annotations
+type Rule struct {
+	Name       string `json:"name" yaml:"name"`
+	Expression string `json:"expression" yaml:"expression"`
+	// Message describes the problem and is shown for every manifest the rule matches
+	Message string `json:"message" yaml:"message"`
+	// Component is the component of the outputs of the rule, see --components
+	Component string `json:"component" yaml:"component"`
+	// Severity is one of error, warn or info, and sets the exit code like a policy rule
+	Severity Severity `json:"severity" yaml:"severity"`
+	// program is the compiled expression
+	program cel.Program
+}
+
+// LoadRules reads a rules file and compiles the expressions
+func LoadRules(file string) (*Rules, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	rules := &Rules{}
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(rules); err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err.Error())
+	}
+	if err := rules.compile(); err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err.Error())
+	}
+	return rules, nil
+}
+
+// compile validates the rules and compiles their expressions
+func (rules *Rules) compile() error {
+	if len(rules.Rules) == 0 {
+		return fmt.Errorf("a rules file must have at least one rule")
+	}
+	env, err := cel.NewEnv(cel.Variable("object", cel.DynType))
+	if err != nil {
+		return err
+	}
+	names := map[string]bool{}
+	for i := range rules.Rules {
+		rule := &rules.Rules[i]
+		if rule.Name == "" {
+			return fmt.Errorf("rule %d must have a name", i+1)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("rule %s is defined more than once", rule.Name)
+		}
+		names[rule.Name] = true
+		if rule.Expression == "" || rule.Message == "" || rule.Component == "" {
+			return fmt.Errorf("rule %s must have an expression, a message and a component", rule.Name)
+		}
+		if _, found := severityRanks[rule.Severity]; !found {
+			return fmt.Errorf("rule %s: invalid severity %q, must be one of error, warn or info", rule.Name, rule.Severity)
+		}
+		ast, issues := env.Compile(rule.Expression)
+		if issues != nil && issues.Err() != nil {
+			return fmt.Errorf("rule %s: %s", rule.Name, issues.Err().Error())
+		}
+		if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+			return fmt.Errorf("rule %s: expression must be a bool, not %s", rule.Name, ast.OutputType())
+		}
+		rule.program, err = env.Program(ast)
+		if err != nil {
+			return fmt.Errorf("rule %s: %s", rule.Name, err.Error())
+		}
+	}
+	return nil
+}
+
+// Components returns the components of the rules, in order
+func (rules *Rules) Components() []string {
+	var components []string
+	for _, rule := range rules.Rules {
+		if !StringInSlice(rule.Component, components) {
+			components = append(components, rule.Component)
+		}
+	}
+	return components
+}
+
+// matches returns whether the expression of the rule is true for object. Expressions
+// that fail, such as by selecting a field the object does not have, do not match.
+func (rule *Rule) matches(object map[string]interface{}) bool {
+	result, _, err := rule.program.Eval(map[string]interface{}{"object": object})
+	if err != nil {
+		klog.V(3).Infof("rule %s did not evaluate: %s", rule.Name, err.Error())
+		return false
+	}
+	matched, ok := result.Value().(bool)
+	if !ok {
+		klog.V(3).Infof("rule %s returned %v instead of a bool", rule.Name, result.Value())
+		return false
+	}
+	return matched
+}
+
+// outputsOf returns an output for every rule that matches the manifest
+func (rules *Rules) outputsOf(m manifest) []*Output {
+	if rules == nil || m.node == nil {
+		return nil
+	}
+	var object map[string]interface{}
+	if err := documentContent(m.node).Decode(&object); err != nil || object == nil {
+		klog.V(3).Infof("skipping rules for %s: could not decode the manifest", m.stub.Metadata.Name)
+		return nil
+	}
+	var outputs []*Output
+	for i := range rules.Rules {
+		rule := &rules.Rules[i]
+		if !rule.matches(object) {
+			continue
+		}
+		outputs = append(outputs, &Output{
+			Name:      m.stub.Metadata.Name,
+			Namespace: m.stub.Metadata.Namespace,
+			Document:  m.stub.Document,
+			Line:      m.stub.Line,
+			Column:    m.stub.Column,
+			APIVersion: &Version{
+				Name:      m.stub.APIVersion,
+				Kind:      m.stub.Kind,
+				Component: rule.Component,
+			},
+			Rule:     rule.Name,
+			Message:  rule.Message,
+			Severity: rule.Severity,
+			ignore:   ignoreRuleOf(m.stub),
+		})
+	}
+	return outputs
+}
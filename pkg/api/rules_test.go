@@ -0,0 +1,191 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testRules = `rules:
+- name: ingress-class-annotation
+  expression: object.kind == "Ingress" && has(object.metadata.annotations) && "kubernetes.io/ingress.class" in object.metadata.annotations
+  message: use spec.ingressClassName instead of the kubernetes.io/ingress.class annotation
+  component: platform
+  severity: warn
+- name: prod-pdb-policy-v1
+  expression: object.kind == "PodDisruptionBudget" && object.metadata.namespace.startsWith("prod-") && object.apiVersion != "policy/v1"
+  message: PodDisruptionBudgets in prod namespaces must be policy/v1
+  component: platform
+  severity: error
+`
+
+func writeRules(t *testing.T, data string) string {
+	file := filepath.Join(t.TempDir(), "rules.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte(data), 0644))
+	return file
+}
+
+func TestLoadRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			data: testRules,
+		},
+		{
+			name:    "no rules",
+			data:    "rules: []\n",
+			wantErr: "a rules file must have at least one rule",
+		},
+		{
+			name: "missing message",
+			data: `rules:
+- name: no-message
+  expression: object.kind == "Ingress"
+  component: platform
+  severity: warn
+`,
+			wantErr: "rule no-message must have an expression, a message and a component",
+		},
+		{
+			name: "duplicate",
+			data: `rules:
+- {name: twice, expression: "true", message: m, component: platform, severity: info}
+- {name: twice, expression: "false", message: m, component: platform, severity: info}
+`,
+			wantErr: "rule twice is defined more than once",
+		},
+		{
+			name: "invalid severity",
+			data: `rules:
+- {name: fatal, expression: "true", message: m, component: platform, severity: fatal}
+`,
+			wantErr: `rule fatal: invalid severity "fatal", must be one of error, warn or info`,
+		},
+		{
+			name: "not a bool",
+			data: `rules:
+- {name: string, expression: "'yes'", message: m, component: platform, severity: info}
+`,
+			wantErr: "rule string: expression must be a bool, not string",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeRules(t, tt.data)
+			rules, err := LoadRules(file)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, file+": "+tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"platform"}, rules.Components())
+		})
+	}
+
+	_, err := LoadRules(writeRules(t, `rules:
+- {name: syntax, expression: "object.kind ==", message: m, component: platform, severity: info}
+`))
+	assert.ErrorContains(t, err, "rule syntax: ERROR: <input>:1:15: Syntax error")
+}
+
+func TestInstance_IsVersioned_rules(t *testing.T) {
+	rules, err := LoadRules(writeRules(t, testRules))
+	assert.NoError(t, err)
+	instance := &Instance{
+		DeprecatedVersions: []Version{
+			{Name: "extensions/v1beta1", Kind: "Ingress", DeprecatedIn: "v1.14.0", RemovedIn: "v1.22.0", Component: "k8s"},
+		},
+		Rules: rules,
+	}
+	data := []byte(`apiVersion: extensions/v1beta1
+kind: Ingress
+metadata:
+  name: legacy
+  annotations:
+    kubernetes.io/ingress.class: nginx
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: modern
+spec:
+  ingressClassName: nginx
+---
+apiVersion: policy/v1beta1
+kind: PodDisruptionBudget
+metadata:
+  name: web
+  namespace: prod-web
+---
+apiVersion: policy/v1beta1
+kind: PodDisruptionBudget
+metadata:
+  name: web
+  namespace: staging-web
+---
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: no-namespace
+`)
+	outputs, err := instance.IsVersioned(data)
+	assert.NoError(t, err)
+	assert.Len(t, outputs, 3)
+
+	assert.Equal(t, "", outputs[0].Rule)
+	assert.Equal(t, "k8s", outputs[0].APIVersion.Component)
+
+	assert.Equal(t, &Output{
+		Name:       "legacy",
+		Document:   1,
+		Line:       1,
+		Column:     1,
+		APIVersion: &Version{Name: "extensions/v1beta1", Kind: "Ingress", Component: "platform"},
+		Rule:       "ingress-class-annotation",
+		Message:    "use spec.ingressClassName instead of the kubernetes.io/ingress.class annotation",
+		Severity:   SeverityWarn,
+	}, outputs[1])
+
+	assert.Equal(t, "prod-pdb-policy-v1", outputs[2].Rule)
+	assert.Equal(t, "prod-web", outputs[2].Namespace)
+	assert.Equal(t, SeverityError, outputs[2].Severity)
+}
+
+func TestPolicy_returnCode_rules(t *testing.T) {
+	warn := &Output{Rule: "warn", Severity: SeverityWarn, APIVersion: &Version{Component: "platform"}}
+	info := &Output{Rule: "info", Severity: SeverityInfo, APIVersion: &Version{Component: "platform"}}
+	removedOnly := &Policy{Rules: []PolicyRule{{Match: PolicyMatch{Removed: boolPtr(true)}, Severity: SeverityError}}}
+
+	assert.Equal(t, 0, removedOnly.returnCode([]*Output{info}, policyTargets))
+	assert.Equal(t, 2, removedOnly.returnCode([]*Output{info, warn}, policyTargets))
+	assert.Equal(t, 3, removedOnly.returnCode([]*Output{warn, policyRemoved}, policyTargets))
+	assert.Equal(t, 0, removedOnly.returnCode([]*Output{{Rule: "warn", Severity: SeverityWarn, Suppressed: true, APIVersion: &Version{}}}, policyTargets))
+}
+
+func Test_ignoreRule_suppresses_rules(t *testing.T) {
+	output := &Output{Rule: "ingress-class-annotation", Severity: SeverityWarn}
+	assert.False(t, (&ignoreRule{deprecations: true}).suppresses(output, time.Now()))
+	assert.False(t, (&ignoreRule{removals: true}).suppresses(output, time.Now()))
+	assert.True(t, (&ignoreRule{deprecations: true, removals: true}).suppresses(output, time.Now()))
+}
@@ -0,0 +1,288 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	// sarifInformationURI is where the tool is described in the SARIF output
+	sarifInformationURI = "https://github.com/danielpickens/lamb"
+)
+
+// sarifLog is the root of a SARIF file, see https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	Help             sarifMessage `json:"help"`
+	Properties       sarifTags    `json:"properties"`
+}
+
+type sarifTags struct {
+	Tags []string `json:"tags,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID        string             `json:"ruleId"`
+	RuleIndex     int                `json:"ruleIndex"`
+	Level         string             `json:"level"`
+	Message       sarifMessage       `json:"message"`
+	Locations     []sarifLocation    `json:"locations"`
+	Suppressions  []sarifSuppression `json:"suppressions,omitempty"`
+	BaselineState string             `json:"baselineState,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+type sarifSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+// sarifBaselineStates are the SARIF baseline states of the changes of Compare
+var sarifBaselineStates = map[Change]string{
+	ChangeNew:       "new",
+	ChangeUnchanged: "unchanged",
+	ChangeFixed:     "absent",
+}
+
+// sarifRuleID returns the id of the SARIF rule of an output, component/kind/apiVersion,
+// followed by the field for deprecated fields, or component/name for rules
+func sarifRuleID(output *Output) string {
+	if output.Rule != "" {
+		return output.APIVersion.Component + "/" + output.Rule
+	}
+	id := fmt.Sprintf("%s/%s/%s", output.APIVersion.Component, output.APIVersion.Kind, output.APIVersion.Name)
+	if output.Field != "" {
+		id = id + "/" + output.Field
+	}
+	return id
+}
+
+// newSarifRule returns the SARIF rule describing the finding of an output
+func newSarifRule(output *Output) sarifRule {
+	version := output.APIVersion
+	rule := sarifRule{
+		ID:         sarifRuleID(output),
+		Properties: sarifTags{Tags: []string{version.Component}},
+	}
+	if output.Rule != "" {
+		rule.ShortDescription.Text = output.Rule
+		rule.Help.Text = output.Message
+		return rule
+	}
+	subject := fmt.Sprintf("%s %s", version.Kind, version.Name)
+	if output.Field != "" {
+		subject = fmt.Sprintf("Field %s of %s", output.Field, subject)
+	}
+	var changes []string
+	if version.DeprecatedIn != "" {
+		changes = append(changes, "deprecated in "+version.DeprecatedIn)
+	}
+	if version.RemovedIn != "" {
+		changes = append(changes, "removed in "+version.RemovedIn)
+	}
+	if len(changes) == 0 {
+		changes = append(changes, "deprecated")
+	}
+	rule.ShortDescription.Text = fmt.Sprintf("%s is deprecated", subject)
+	help := fmt.Sprintf("%s is %s.", subject, strings.Join(changes, " and "))
+	switch {
+	case version.ReplacementAPI != "" && version.ReplacementAvailableIn != "":
+		help = fmt.Sprintf("%s Replace it with %s, available in %s.", help, version.ReplacementAPI, version.ReplacementAvailableIn)
+	case version.ReplacementAPI != "":
+		help = fmt.Sprintf("%s Replace it with %s.", help, version.ReplacementAPI)
+	default:
+		help = help + " There is no replacement."
+	}
+	rule.Help.Text = help
+	return rule
+}
+
+// sarifLevel returns the SARIF level of an output from its severity, or from whether it
+// is removed or deprecated if it has none
+func sarifLevel(output *Output) string {
+	switch output.Severity {
+	case SeverityError:
+		return "error"
+	case SeverityWarn:
+		return "warning"
+	case SeverityInfo:
+		return "note"
+	}
+	if output.Removed {
+		return "error"
+	}
+	return "warning"
+}
+
+// findingMessage returns a sentence describing the finding of an output
+func findingMessage(output *Output) string {
+	if output.Rule != "" {
+		return fmt.Sprintf("%s %s: %s", output.APIVersion.Kind, output.Name, output.Message)
+	}
+	subject := output.APIVersion.Name
+	if output.Field != "" {
+		subject = fmt.Sprintf("%s field %s", subject, output.Field)
+	}
+	state := "deprecated"
+	if output.Removed {
+		state = "removed"
+	}
+	message := fmt.Sprintf("%s %s uses %s, which is %s", output.APIVersion.Kind, output.Name, subject, state)
+	if output.APIVersion.ReplacementAPI != "" {
+		return fmt.Sprintf("%s. Replace it with %s.", message, output.APIVersion.ReplacementAPI)
+	}
+	return message + "."
+}
+
+// sarifLocationOf returns the file of an output, or the release or namespace and name of
+// outputs from Helm and the cluster, which have no file
+func sarifLocationOf(output *Output) sarifLocation {
+	if output.FilePath != "" {
+		uri := &url.URL{Path: filepath.ToSlash(output.FilePath)}
+		if filepath.IsAbs(output.FilePath) {
+			uri.Scheme = "file"
+		}
+		location := &sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: uri.String()}}
+		if output.Line > 0 {
+			location.Region = &sarifRegion{StartLine: output.Line, StartColumn: output.Column}
+		}
+		return sarifLocation{PhysicalLocation: location}
+	}
+	name := output.Name
+	if output.Namespace != "" {
+		name = output.Namespace + "/" + output.Name
+	}
+	return sarifLocation{LogicalLocations: []sarifLogicalLocation{{
+		Name:               output.Name,
+		FullyQualifiedName: name,
+		Kind:               "resource",
+	}}}
+}
+
+// sarifSuppressionsOf returns the suppression of an output, if it is suppressed. Baselines
+// are external, annotations and comments are in the source.
+func sarifSuppressionsOf(output *Output) []sarifSuppression {
+	if !output.Suppressed {
+		return nil
+	}
+	kind := "inSource"
+	if strings.HasPrefix(output.SuppressedBy, "baseline") {
+		kind = "external"
+	}
+	return []sarifSuppression{{Kind: kind, Justification: output.SuppressedBy}}
+}
+
+// sarif returns the outputs as a SARIF log with a rule for every version and deprecated
+// field of the components, and for any other finding of the outputs
+func (instance *Instance) sarif() sarifLog {
+	driver := sarifDriver{Name: "lamb", InformationURI: sarifInformationURI, Rules: []sarifRule{}}
+	ruleIndexes := map[string]int{}
+	addRule := func(output *Output) int {
+		rule := newSarifRule(output)
+		if index, found := ruleIndexes[rule.ID]; found {
+			return index
+		}
+		ruleIndexes[rule.ID] = len(driver.Rules)
+		driver.Rules = append(driver.Rules, rule)
+		return ruleIndexes[rule.ID]
+	}
+	for i := range instance.DeprecatedVersions {
+		version := &instance.DeprecatedVersions[i]
+		if len(instance.Components) > 0 && !StringInSlice(version.Component, instance.Components) {
+			continue
+		}
+		addRule(&Output{APIVersion: version})
+		for _, field := range version.Fields {
+			addRule(&Output{APIVersion: field.version(version), Field: field.Path})
+		}
+	}
+
+	results := []sarifResult{}
+	for _, output := range instance.Outputs {
+		if output.APIVersion == nil {
+			continue
+		}
+		results = append(results, sarifResult{
+			RuleID:        sarifRuleID(output),
+			RuleIndex:     addRule(output),
+			Level:         sarifLevel(output),
+			Message:       sarifMessage{Text: findingMessage(output)},
+			Locations:     []sarifLocation{sarifLocationOf(output)},
+			Suppressions:  sarifSuppressionsOf(output),
+			BaselineState: sarifBaselineStates[output.Change],
+		})
+	}
+	return sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+}
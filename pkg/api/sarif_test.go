@@ -0,0 +1,104 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstance_sarif(t *testing.T) {
+	deployment := Version{
+		Name: "extensions/v1beta1", Kind: "Deployment", DeprecatedIn: "v1.9.0", RemovedIn: "v1.16.0",
+		ReplacementAPI: "apps/v1", ReplacementAvailableIn: "v1.9.0", Component: "k8s",
+		Fields: []Field{{Path: "spec.rollbackTo", DeprecatedIn: "v1.9.0"}},
+	}
+	gateway := Version{Name: "networking.istio.io/v1alpha3", Kind: "Gateway", DeprecatedIn: "v1.20.0", Component: "istio"}
+	instance := &Instance{
+		DeprecatedVersions: []Version{deployment, gateway},
+		Components:         []string{"k8s"},
+		Outputs: []*Output{
+			{Name: "web", FilePath: "manifests/deploy.yaml", Line: 3, Column: 1, APIVersion: &deployment, Removed: true, Deprecated: true, Change: ChangeNew},
+			{Name: "web", FilePath: "/abs/deploy file.yaml", APIVersion: deployment.Fields[0].version(&deployment), Field: "spec.rollbackTo", Deprecated: true},
+			{Name: "release/web", Namespace: "apps", APIVersion: &deployment, Removed: true, Suppressed: true, SuppressedBy: "baseline: later (owner me, expires 2030-01-01)"},
+			{Name: "gw", Namespace: "istio-system", APIVersion: &gateway, Deprecated: true, Severity: SeverityInfo, Suppressed: true, SuppressedBy: "comment # lamb:ignore"},
+			{Name: "legacy", FilePath: "ingress.yaml", APIVersion: &Version{Name: "extensions/v1beta1", Kind: "Ingress", Component: "platform"}, Rule: "ingress-class", Message: "use spec.ingressClassName", Severity: SeverityWarn},
+		},
+	}
+
+	log := instance.sarif()
+	assert.Equal(t, "2.1.0", log.Version)
+	driver := log.Runs[0].Tool.Driver
+	var ids []string
+	for _, rule := range driver.Rules {
+		ids = append(ids, rule.ID)
+	}
+	assert.Equal(t, []string{
+		"k8s/Deployment/extensions/v1beta1",
+		"k8s/Deployment/extensions/v1beta1/spec.rollbackTo",
+		"istio/Gateway/networking.istio.io/v1alpha3",
+		"platform/ingress-class",
+	}, ids)
+	assert.Equal(t, "Deployment extensions/v1beta1 is deprecated in v1.9.0 and removed in v1.16.0. Replace it with apps/v1, available in v1.9.0.", driver.Rules[0].Help.Text)
+	assert.Equal(t, "Field spec.rollbackTo of Deployment extensions/v1beta1 is deprecated in v1.9.0. There is no replacement.", driver.Rules[1].Help.Text)
+	assert.Equal(t, "use spec.ingressClassName", driver.Rules[3].Help.Text)
+
+	results := log.Runs[0].Results
+	assert.Len(t, results, 5)
+
+	assert.Equal(t, sarifResult{
+		RuleID:    "k8s/Deployment/extensions/v1beta1",
+		RuleIndex: 0,
+		Level:     "error",
+		Message:   sarifMessage{Text: "Deployment web uses extensions/v1beta1, which is removed. Replace it with apps/v1."},
+		Locations: []sarifLocation{{PhysicalLocation: &sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: "manifests/deploy.yaml"},
+			Region:           &sarifRegion{StartLine: 3, StartColumn: 1},
+		}}},
+		BaselineState: "new",
+	}, results[0])
+
+	assert.Equal(t, 1, results[1].RuleIndex)
+	assert.Equal(t, "warning", results[1].Level)
+	assert.Equal(t, "file:///abs/deploy%20file.yaml", results[1].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Nil(t, results[1].Locations[0].PhysicalLocation.Region)
+
+	assert.Equal(t, []sarifLogicalLocation{{Name: "release/web", FullyQualifiedName: "apps/release/web", Kind: "resource"}}, results[2].Locations[0].LogicalLocations)
+	assert.Equal(t, []sarifSuppression{{Kind: "external", Justification: "baseline: later (owner me, expires 2030-01-01)"}}, results[2].Suppressions)
+
+	assert.Equal(t, 2, results[3].RuleIndex)
+	assert.Equal(t, "note", results[3].Level)
+	assert.Equal(t, "inSource", results[3].Suppressions[0].Kind)
+
+	assert.Equal(t, "platform/ingress-class", results[4].RuleID)
+	assert.Equal(t, 3, results[4].RuleIndex)
+	assert.Equal(t, "warning", results[4].Level)
+	assert.Equal(t, "Ingress legacy: use spec.ingressClassName", results[4].Message.Text)
+}
+
+func TestInstance_sarif_empty(t *testing.T) {
+	data, err := json.Marshal((&Instance{}).sarif())
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"version": "2.1.0",
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"runs": [{
+			"tool": {"driver": {"name": "lamb", "informationUri": "https://github.com/danielpickens/lamb", "rules": []}},
+			"results": []
+		}]
+	}`, string(data))
+}
@@ -0,0 +1,187 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"golang.org/x/mod/semver"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+const (
+	// TemplateOutput is the output format of -o go-template= and -o go-template-file=
+	TemplateOutput = "go-template"
+	// JSONPathOutput is the output format of -o jsonpath=
+	JSONPathOutput = "jsonpath"
+
+	templateFilePrefix = "go-template-file="
+)
+
+// SplitTemplateOutput splits an output of go-template=, go-template-file= or jsonpath= into
+// its output format and template, reading the file of go-template-file=. The template is
+// parsed so that errors in it are reported before anything is scanned. found is false for
+// any other output.
+func SplitTemplateOutput(output string) (format string, text string, found bool, err error) {
+	switch {
+	case strings.HasPrefix(output, templateFilePrefix):
+		file := strings.TrimPrefix(output, templateFilePrefix)
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", "", true, err
+		}
+		format, text = TemplateOutput, string(data)
+	case strings.HasPrefix(output, TemplateOutput+"="):
+		format, text = TemplateOutput, strings.TrimPrefix(output, TemplateOutput+"=")
+	case strings.HasPrefix(output, JSONPathOutput+"="):
+		format, text = JSONPathOutput, strings.TrimPrefix(output, JSONPathOutput+"=")
+	default:
+		return "", "", false, nil
+	}
+	if text == "" {
+		return "", "", true, fmt.Errorf("--output %s requires a template", format)
+	}
+	if format == JSONPathOutput {
+		_, err = parseJSONPath(text)
+	} else {
+		_, err = parseTemplate(text)
+	}
+	if err != nil {
+		return "", "", true, fmt.Errorf("invalid %s template: %w", format, err)
+	}
+	return format, text, true, nil
+}
+
+// templateFuncs are the functions available to -o go-template
+var templateFuncs = template.FuncMap{
+	"join":          templateJoin,
+	"semverCompare": semverCompare,
+	"upper":         strings.ToUpper,
+	"lower":         strings.ToLower,
+}
+
+// templateJoin joins the elements of a list with a separator, taking the list last so that
+// it can be piped, as in {{ .names | join ", " }}
+func templateJoin(separator string, list interface{}) string {
+	switch list := list.(type) {
+	case nil:
+		return ""
+	case []string:
+		return strings.Join(list, separator)
+	case []interface{}:
+		elements := make([]string, 0, len(list))
+		for _, element := range list {
+			elements = append(elements, fmt.Sprint(element))
+		}
+		return strings.Join(elements, separator)
+	}
+	return fmt.Sprint(list)
+}
+
+// semverCompare returns whether a version satisfies a constraint made of an operator, one of
+// =, !=, <, <=, > or >=, and a version, as in {{ if semverCompare "<=v1.25.0" .version }}.
+// The operator defaults to =, and the leading v of either version is optional.
+func semverCompare(constraint string, version string) (bool, error) {
+	constraint = strings.TrimSpace(constraint)
+	operator := "="
+	for _, op := range []string{"!=", "<=", ">=", "=", "<", ">"} {
+		if strings.HasPrefix(constraint, op) {
+			operator = op
+			constraint = strings.TrimSpace(strings.TrimPrefix(constraint, op))
+			break
+		}
+	}
+	want, got := withVPrefix(constraint), withVPrefix(version)
+	if !semver.IsValid(want) {
+		return false, fmt.Errorf("invalid version %q in constraint", constraint)
+	}
+	if !semver.IsValid(got) {
+		return false, nil
+	}
+	result := semver.Compare(got, want)
+	switch operator {
+	case "!=":
+		return result != 0, nil
+	case "<":
+		return result < 0, nil
+	case "<=":
+		return result <= 0, nil
+	case ">":
+		return result > 0, nil
+	case ">=":
+		return result >= 0, nil
+	}
+	return result == 0, nil
+}
+
+// withVPrefix adds the leading v of a semantic version if it is missing
+func withVPrefix(version string) string {
+	if version == "" || strings.HasPrefix(version, "v") {
+		return version
+	}
+	return "v" + version
+}
+
+func parseTemplate(text string) (*template.Template, error) {
+	return template.New("output").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+}
+
+// parseJSONPath parses a JSONPath expression, which like kubectl does not need the braces
+// around a single expression
+func parseJSONPath(text string) (*jsonpath.JSONPath, error) {
+	if !strings.Contains(text, "{") {
+		text = "{" + text + "}"
+	}
+	j := jsonpath.New("output").AllowMissingKeys(true)
+	return j, j.Parse(text)
+}
+
+// templateData returns the instance as the generic JSON of -o json, so that templates use
+// the same field names
+func (instance *Instance) templateData() (interface{}, error) {
+	data, err := json.Marshal(instance)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	err = json.Unmarshal(data, &generic)
+	return generic, err
+}
+
+// writeTemplate writes the outputs to w with the go-template or jsonpath template of
+// OutputTemplate
+func (instance *Instance) writeTemplate(w io.Writer) error {
+	data, err := instance.templateData()
+	if err != nil {
+		return err
+	}
+	if instance.OutputFormat == JSONPathOutput {
+		j, err := parseJSONPath(instance.OutputTemplate)
+		if err != nil {
+			return err
+		}
+		return j.Execute(w, data)
+	}
+	t, err := parseTemplate(instance.OutputTemplate)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, data)
+}
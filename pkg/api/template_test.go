@@ -0,0 +1,153 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitTemplateOutput(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "report.tmpl")
+	assert.NoError(t, os.WriteFile(file, []byte("{{ range .items }}{{ .name }}{{ end }}"), 0644))
+
+	tests := []struct {
+		name      string
+		output    string
+		format    string
+		text      string
+		found     bool
+		wantError string
+	}{
+		{name: "other output", output: "json"},
+		{name: "go-template", output: "go-template={{ .items }}", format: TemplateOutput, text: "{{ .items }}", found: true},
+		{name: "go-template-file", output: "go-template-file=" + file, format: TemplateOutput, text: "{{ range .items }}{{ .name }}{{ end }}", found: true},
+		{name: "jsonpath", output: "jsonpath={.items[*].name}", format: JSONPathOutput, text: "{.items[*].name}", found: true},
+		{name: "empty template", output: "go-template=", found: true, wantError: "--output go-template requires a template"},
+		{name: "invalid go-template", output: "go-template={{ .items", found: true, wantError: "invalid go-template template: template: output:1: unclosed action"},
+		{name: "unknown function", output: "go-template={{ nope }}", found: true, wantError: `invalid go-template template: template: output:1: function "nope" not defined`},
+		{name: "invalid jsonpath", output: "jsonpath={.items[}", found: true, wantError: "invalid jsonpath template: unterminated array"},
+		{name: "missing file", output: "go-template-file=" + file + ".missing", found: true, wantError: "open " + file + ".missing: no such file or directory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, text, found, err := SplitTemplateOutput(tt.output)
+			assert.Equal(t, tt.found, found)
+			if tt.wantError != "" {
+				assert.EqualError(t, err, tt.wantError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.format, format)
+			assert.Equal(t, tt.text, text)
+		})
+	}
+}
+
+func TestSemverCompare(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+		wantError  bool
+	}{
+		{constraint: "v1.16.0", version: "v1.16.0", want: true},
+		{constraint: "=1.16.0", version: "v1.16.0", want: true},
+		{constraint: "!=v1.16.0", version: "v1.16.0", want: false},
+		{constraint: "<v1.25.0", version: "v1.16.0", want: true},
+		{constraint: "<= v1.25.0", version: "v1.25.0", want: true},
+		{constraint: ">v1.25.0", version: "v1.25.0", want: false},
+		{constraint: ">=1.22", version: "v1.25.0", want: true},
+		{constraint: ">=v1.22.0", version: "", want: false},
+		{constraint: ">=later", version: "v1.25.0", wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint+" "+tt.version, func(t *testing.T) {
+			got, err := semverCompare(tt.constraint, tt.version)
+			if tt.wantError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestInstance_writeTemplate(t *testing.T) {
+	outputs := []*Output{
+		{Name: "web", Namespace: "apps", APIVersion: &Version{Name: "extensions/v1beta1", Kind: "Deployment", RemovedIn: "v1.16.0", ReplacementAPI: "apps/v1", Component: "k8s"}, Deprecated: true, Removed: true},
+		{Name: "restricted", APIVersion: &Version{Name: "policy/v1beta1", Kind: "PodSecurityPolicy", RemovedIn: "v1.25.0", Component: "k8s"}, Deprecated: true},
+	}
+	tests := []struct {
+		name     string
+		format   string
+		template string
+		outputs  []*Output
+		want     string
+	}{
+		{
+			name:     "go-template",
+			format:   TemplateOutput,
+			template: `{{ range .items }}{{ if semverCompare "<=v1.22.0" (index .api "removed-in") }}{{ .api.kind | upper }} {{ .name }}{{ "\n" }}{{ end }}{{ end }}`,
+			outputs:  outputs,
+			want:     "DEPLOYMENT web\n",
+		},
+		{
+			name:     "jsonpath",
+			format:   JSONPathOutput,
+			template: `{range .items[*]}{.api.kind}/{.name}{"\n"}{end}`,
+			outputs:  outputs,
+			want:     "Deployment/web\nPodSecurityPolicy/restricted\n",
+		},
+		{
+			name:     "jsonpath without braces",
+			format:   JSONPathOutput,
+			template: `.target-versions.k8s`,
+			outputs:  outputs,
+			want:     "v1.22.0",
+		},
+		{
+			name:     "no outputs",
+			format:   TemplateOutput,
+			template: `{{ range .items }}{{ .name }}{{ else }}no findings for k8s {{ index . "target-versions" "k8s" }}{{ end }}`,
+			want:     "no findings for k8s v1.22.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := &Instance{
+				Outputs:        tt.outputs,
+				OutputFormat:   tt.format,
+				OutputTemplate: tt.template,
+				TargetVersions: map[string]string{"k8s": "v1.22.0"},
+			}
+			var buf bytes.Buffer
+			assert.NoError(t, instance.writeTemplate(&buf))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestTemplateJoin(t *testing.T) {
+	assert.Equal(t, "", templateJoin(", ", nil))
+	assert.Equal(t, "a, b", templateJoin(", ", []string{"a", "b"}))
+	assert.Equal(t, "a, 1, true", templateJoin(", ", []interface{}{"a", 1, true}))
+	assert.Equal(t, "a", templateJoin(", ", "a"))
+}
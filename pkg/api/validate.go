@@ -0,0 +1,387 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/semver"
+	"gopkg.in/yaml.v3"
+)
+
+// VersionFileError is a problem found in a versions file
+type VersionFileError struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e VersionFileError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+// VersionFileErrors is every problem found in a versions file
+type VersionFileErrors []VersionFileError
+
+func (e VersionFileErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// versionFileValidator collects the problems of a single versions file
+type versionFileValidator struct {
+	file   string
+	errors VersionFileErrors
+}
+
+func (v *versionFileValidator) addError(node *yaml.Node, format string, args ...interface{}) {
+	v.errors = append(v.errors, VersionFileError{
+		File:    v.file,
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// versionEntry is a deprecated version along with the node it was decoded from
+type versionEntry struct {
+	version Version
+	node    *yaml.Node
+}
+
+// ValidateVersionFile checks a versions file and returns every problem found, or nil.
+// It rejects unknown keys and invalid semver, checks that replacement-available-in,
+// deprecated-in and removed-in are in order, that a replacement-api is not removed along
+// with the version it replaces, that every component has a target version in the file
+// or in targetVersions, and that no kind and version are added twice.
+// file is only used in the errors.
+func ValidateVersionFile(file string, data []byte, targetVersions map[string]string) VersionFileErrors {
+	v := &versionFileValidator{file: file}
+	var document yaml.Node
+	err := yaml.Unmarshal(data, &document)
+	if err != nil {
+		v.errors = append(v.errors, VersionFileError{File: file, Message: err.Error()})
+		return v.errors
+	}
+	root := documentContent(&document)
+	if root.Kind == 0 {
+		// an empty file
+		return nil
+	}
+	if root.Kind != yaml.MappingNode {
+		v.addError(root, "a versions file must be a mapping")
+		return v.errors
+	}
+	v.checkKeys(root, reflect.TypeOf(VersionFile{}))
+
+	targets := map[string]string{}
+	if node := mappingValue(root, "target-versions"); node != nil {
+		if node.Kind != yaml.MappingNode {
+			v.addError(node, "target-versions must be a mapping of component to version")
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v.checkSemver(node.Content[i+1], "target version of "+node.Content[i].Value)
+			targets[node.Content[i].Value] = node.Content[i+1].Value
+		}
+	}
+	if node := mappingValue(root, "platforms"); node != nil {
+		v.checkPlatforms(node)
+	}
+	if node := mappingValue(root, "component-probes"); node != nil {
+		v.checkComponentProbes(node)
+	}
+	for component, version := range targetVersions {
+		if _, found := targets[component]; !found {
+			targets[component] = version
+		}
+	}
+
+	var entries []versionEntry
+	if node := mappingValue(root, "deprecated-versions"); node != nil {
+		if node.Kind != yaml.SequenceNode {
+			v.addError(node, "deprecated-versions must be a list")
+			return v.errors
+		}
+		for _, item := range node.Content {
+			entry, ok := v.checkVersion(item)
+			if ok {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	v.checkReplacements(entries)
+	v.checkDuplicates(entries)
+	v.checkTargets(entries, targets)
+
+	if len(v.errors) == 0 {
+		return nil
+	}
+	sort.SliceStable(v.errors, func(i, j int) bool {
+		return v.errors[i].Line < v.errors[j].Line
+	})
+	return v.errors
+}
+
+// UnMarshalVersionsStrict is UnMarshalVersions for files that must pass ValidateVersionFile
+func UnMarshalVersionsStrict(file string, data []byte, targetVersions map[string]string) ([]Version, map[string]string, error) {
+	versionFile, err := UnMarshalVersionFileStrict(file, data, targetVersions)
+	if err != nil {
+		return nil, nil, err
+	}
+	return versionFile.DeprecatedVersions, versionFile.TargetVersions, nil
+}
+
+// UnMarshalVersionFileStrict is UnMarshalVersionFile for files that must pass ValidateVersionFile
+func UnMarshalVersionFileStrict(file string, data []byte, targetVersions map[string]string) (*VersionFile, error) {
+	if errs := ValidateVersionFile(file, data, targetVersions); errs != nil {
+		return nil, errs
+	}
+	return UnMarshalVersionFile(data)
+}
+
+// checkVersion checks a single entry of deprecated-versions and decodes it
+func (v *versionFileValidator) checkVersion(node *yaml.Node) (versionEntry, bool) {
+	if node.Kind != yaml.MappingNode {
+		v.addError(node, "each deprecated version must be a mapping")
+		return versionEntry{}, false
+	}
+	v.checkKeys(node, reflect.TypeOf(Version{}))
+	entry := versionEntry{node: node}
+	err := node.Decode(&entry.version)
+	if err != nil {
+		v.addError(node, "%s", err.Error())
+		return versionEntry{}, false
+	}
+	version := entry.version
+
+	if version.Name == "" {
+		v.addError(node, "version is required")
+	}
+	if version.Action != "" && !StringInSlice(version.Action, versionActions) {
+		v.addError(mappingValue(node, "action"), "invalid action %q, must be one of %v", version.Action, versionActions)
+	}
+	if version.Component == "" && isAdded(version) {
+		v.addError(node, "component is required")
+	}
+	if err := version.validatePatterns(); err != nil {
+		v.addError(node, "%s", err.Error())
+	}
+	v.checkReleases(node, version.ReplacementAvailableIn, version.DeprecatedIn, version.RemovedIn)
+
+	if fields := mappingValue(node, "fields"); fields != nil && fields.Kind == yaml.SequenceNode {
+		for i, fieldNode := range fields.Content {
+			if fieldNode.Kind != yaml.MappingNode {
+				v.addError(fieldNode, "each field must be a mapping")
+				continue
+			}
+			v.checkKeys(fieldNode, reflect.TypeOf(Field{}))
+			if i >= len(version.Fields) {
+				continue
+			}
+			field := version.Fields[i]
+			if field.Path == "" || len(parseFieldPath(field.Path)) == 0 {
+				v.addError(fieldNode, "invalid field path %q", field.Path)
+			}
+			v.checkReleases(fieldNode, field.ReplacementAvailableIn, field.DeprecatedIn, field.RemovedIn)
+		}
+	}
+	return entry, true
+}
+
+// checkReleases checks that the release versions of an entry are valid semver and in order
+func (v *versionFileValidator) checkReleases(node *yaml.Node, replacementAvailableIn string, deprecatedIn string, removedIn string) {
+	valid := true
+	for _, key := range []string{"replacement-available-in", "deprecated-in", "removed-in"} {
+		if value := mappingValue(node, key); value != nil && value.Value != "" {
+			valid = v.checkSemver(value, key) && valid
+		}
+	}
+	if !valid {
+		return
+	}
+	if replacementAvailableIn != "" && deprecatedIn != "" && semver.Compare(replacementAvailableIn, deprecatedIn) > 0 {
+		v.addError(mappingValue(node, "replacement-available-in"), "replacement-available-in %s is after deprecated-in %s", replacementAvailableIn, deprecatedIn)
+	}
+	if deprecatedIn != "" && removedIn != "" && semver.Compare(deprecatedIn, removedIn) > 0 {
+		v.addError(mappingValue(node, "deprecated-in"), "deprecated-in %s is after removed-in %s", deprecatedIn, removedIn)
+	}
+	if replacementAvailableIn != "" && removedIn != "" && semver.Compare(replacementAvailableIn, removedIn) > 0 {
+		v.addError(mappingValue(node, "replacement-available-in"), "replacement-available-in %s is after removed-in %s", replacementAvailableIn, removedIn)
+	}
+}
+
+func (v *versionFileValidator) checkSemver(node *yaml.Node, name string) bool {
+	if semver.IsValid(node.Value) {
+		return true
+	}
+	hint := ""
+	if !strings.HasPrefix(node.Value, "v") && semver.IsValid("v"+node.Value) {
+		hint = " (missing the leading 'v')"
+	}
+	v.addError(node, "%s %q is not valid semver%s", name, node.Value, hint)
+	return false
+}
+
+// checkKeys reports every key of a mapping that is not a yaml key of the struct t
+func (v *versionFileValidator) checkKeys(node *yaml.Node, t reflect.Type) {
+	known := yamlKeys(t)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if !StringInSlice(key.Value, known) {
+			v.addError(key, "unknown key %q, must be one of %v", key.Value, known)
+		}
+	}
+}
+
+// yamlKeys returns the yaml keys of the exported fields of a struct
+func yamlKeys(t reflect.Type) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = strings.ToLower(field.Name)
+		}
+		keys = append(keys, name)
+	}
+	return keys
+}
+
+// checkReplacements reports replacements that are removed no later than the version they replace
+func (v *versionFileValidator) checkReplacements(entries []versionEntry) {
+	for _, entry := range entries {
+		version := entry.version
+		if version.ReplacementAPI == "" {
+			continue
+		}
+		for _, other := range entries {
+			replacement := other.version
+			if replacement.Name != version.ReplacementAPI || replacement.Component != version.Component || replacement.RemovedIn == "" {
+				continue
+			}
+			if replacement.Kind != "" && replacement.Kind != version.Kind {
+				continue
+			}
+			if version.RemovedIn == "" || semver.Compare(replacement.RemovedIn, version.RemovedIn) <= 0 {
+				v.addError(mappingValue(entry.node, "replacement-api"), "replacement-api %s is removed in %s (line %d), no later than %s %s itself", version.ReplacementAPI, replacement.RemovedIn, other.node.Line, version.Kind, version.Name)
+			}
+		}
+	}
+}
+
+// isAdded returns whether a version adds a new version rather than changing an existing one
+func isAdded(version Version) bool {
+	return version.Action == "" || version.Action == VersionActionAdd
+}
+
+// checkDuplicates reports every kind and version that is added more than once
+func (v *versionFileValidator) checkDuplicates(entries []versionEntry) {
+	seen := map[string]int{}
+	for _, entry := range entries {
+		if !isAdded(entry.version) {
+			continue
+		}
+		key := indexKey(entry.version.Name, entry.version.Kind)
+		if line, found := seen[key]; found {
+			v.addError(entry.node, "duplicate kind %q and version %s, first defined on line %d", entry.version.Kind, entry.version.Name, line)
+			continue
+		}
+		seen[key] = entry.node.Line
+	}
+}
+
+// checkTargets reports components without a target version
+func (v *versionFileValidator) checkTargets(entries []versionEntry, targets map[string]string) {
+	reported := map[string]bool{}
+	for _, entry := range entries {
+		component := entry.version.Component
+		if component == "" || reported[component] || entry.version.Action == VersionActionRemove {
+			continue
+		}
+		if _, found := targets[component]; !found {
+			reported[component] = true
+			v.addError(mappingValue(entry.node, "component"), "component %s has no entry in target-versions", component)
+		}
+	}
+}
+
+// checkPlatforms checks that platforms maps platform versions to valid target versions
+func (v *versionFileValidator) checkPlatforms(node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		v.addError(node, "platforms must be a mapping of platform to platform versions")
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		platform, versions := node.Content[i], node.Content[i+1]
+		if platform.Value != strings.ToLower(platform.Value) {
+			v.addError(platform, "platform %s must be lower case", platform.Value)
+		}
+		if versions.Kind != yaml.MappingNode {
+			v.addError(versions, "platform %s must be a mapping of platform versions to target versions", platform.Value)
+			continue
+		}
+		for j := 0; j+1 < len(versions.Content); j += 2 {
+			version, targets := versions.Content[j], versions.Content[j+1]
+			if !IsValidVersion(version.Value) {
+				v.addError(version, "%s version %q is not a valid version", platform.Value, version.Value)
+			}
+			if targets.Kind != yaml.MappingNode {
+				v.addError(targets, "%s %s must be a mapping of component to target version", platform.Value, version.Value)
+				continue
+			}
+			for k := 0; k+1 < len(targets.Content); k += 2 {
+				v.checkSemver(targets.Content[k+1], fmt.Sprintf("%s %s target version of %s", platform.Value, version.Value, targets.Content[k].Value))
+			}
+		}
+	}
+}
+
+// checkComponentProbes checks that component-probes maps components to lists of probes with a selector
+func (v *versionFileValidator) checkComponentProbes(node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		v.addError(node, "component-probes must be a mapping of component to probes")
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		component, probes := node.Content[i], node.Content[i+1]
+		if probes.Kind != yaml.SequenceNode {
+			v.addError(probes, "component-probes of %s must be a list", component.Value)
+			continue
+		}
+		for _, probe := range probes.Content {
+			if probe.Kind != yaml.MappingNode {
+				v.addError(probe, "component probe of %s must be a mapping", component.Value)
+				continue
+			}
+			v.checkKeys(probe, reflect.TypeOf(ComponentProbe{}))
+			if selector := mappingValue(probe, "selector"); selector == nil || selector.Value == "" {
+				v.addError(probe, "component probe of %s is missing a selector", component.Value)
+			}
+		}
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateVersionFile(t *testing.T) {
+	tests := []struct {
+		name           string
+		data           string
+		targetVersions map[string]string
+		want           []string
+	}{
+		{
+			name: "valid",
+			data: `deprecated-versions:
+- version: extensions/v1beta1
+  kind: Ingress
+  deprecated-in: v1.14.0
+  removed-in: v1.22.0
+  replacement-api: networking.k8s.io/v1beta1
+  replacement-available-in: v1.14.0
+  component: k8s
+  fields:
+  - path: spec.backend
+    deprecated-in: v1.19.0
+    removed-in: v1.22.0
+    replacement: spec.defaultBackend
+target-versions:
+  k8s: v1.22.0
+`,
+		},
+		{
+			name: "empty",
+			data: "",
+		},
+		{
+			name: "unknown keys",
+			data: `deprecated-versions:
+- version: extensions/v1beta1
+  kind: Ingress
+  removed_in: v1.22.0
+  component: k8s
+  fields:
+  - path: spec.backend
+    replaced-by: spec.defaultBackend
+target-version:
+  k8s: v1.22.0
+`,
+			targetVersions: map[string]string{"k8s": "v1.22.0"},
+			want: []string{
+				`test.yaml:4:3: unknown key "removed_in", must be one of [version kind deprecated-in removed-in replacement-api replacement-available-in component fields action]`,
+				`test.yaml:8:5: unknown key "replaced-by", must be one of [path deprecated-in removed-in replacement replacement-available-in component]`,
+				`test.yaml:9:1: unknown key "target-version", must be one of [deprecated-versions target-versions target-types platforms component-probes]`,
+			},
+		},
+		{
+			name: "invalid semver",
+			data: `deprecated-versions:
+- version: extensions/v1beta1
+  kind: Ingress
+  deprecated-in: 1.14.0
+  removed-in: v1.22.x
+  component: k8s
+target-versions:
+  k8s: "1.22"
+`,
+			want: []string{
+				`test.yaml:4:18: deprecated-in "1.14.0" is not valid semver (missing the leading 'v')`,
+				`test.yaml:5:15: removed-in "v1.22.x" is not valid semver`,
+				`test.yaml:8:8: target version of k8s "1.22" is not valid semver (missing the leading 'v')`,
+			},
+		},
+		{
+			name: "out of order",
+			data: `deprecated-versions:
+- version: extensions/v1beta1
+  kind: Ingress
+  deprecated-in: v1.22.0
+  removed-in: v1.16.0
+  replacement-available-in: v1.23.0
+  component: k8s
+  fields:
+  - path: spec.backend
+    deprecated-in: v1.22.0
+    removed-in: v1.19.0
+`,
+			targetVersions: map[string]string{"k8s": "v1.22.0"},
+			want: []string{
+				"test.yaml:4:18: deprecated-in v1.22.0 is after removed-in v1.16.0",
+				"test.yaml:6:29: replacement-available-in v1.23.0 is after deprecated-in v1.22.0",
+				"test.yaml:6:29: replacement-available-in v1.23.0 is after removed-in v1.16.0",
+				"test.yaml:10:20: deprecated-in v1.22.0 is after removed-in v1.19.0",
+			},
+		},
+		{
+			name: "replacement removed at the same time",
+			data: `deprecated-versions:
+- version: extensions/v1beta1
+  kind: Ingress
+  removed-in: v1.22.0
+  replacement-api: networking.k8s.io/v1beta1
+  component: k8s
+- version: networking.k8s.io/v1beta1
+  kind: Ingress
+  removed-in: v1.22.0
+  replacement-api: networking.k8s.io/v1
+  component: k8s
+`,
+			targetVersions: map[string]string{"k8s": "v1.22.0"},
+			want: []string{
+				"test.yaml:5:20: replacement-api networking.k8s.io/v1beta1 is removed in v1.22.0 (line 7), no later than Ingress extensions/v1beta1 itself",
+			},
+		},
+		{
+			name: "missing target version and duplicates",
+			data: `deprecated-versions:
+- version: networking.istio.io/v1alpha3
+  kind: Gateway
+  removed-in: v1.20.0
+  component: istio
+- version: networking.istio.io/v1alpha3
+  kind: Gateway
+  removed-in: v1.21.0
+  component: istio
+- version: networking.istio.io/v1alpha3
+  kind: VirtualService
+  removed-in: v1.21.0
+`,
+			targetVersions: map[string]string{"k8s": "v1.22.0"},
+			want: []string{
+				"test.yaml:5:14: component istio has no entry in target-versions",
+				`test.yaml:6:3: duplicate kind "Gateway" and version networking.istio.io/v1alpha3, first defined on line 2`,
+				"test.yaml:10:3: component is required",
+			},
+		},
+		{
+			name: "actions",
+			data: `deprecated-versions:
+- version: extensions/v1beta1
+  kind: Ingress
+  removed-in: v1.21.0
+  action: override
+- version: extensions/v1beta1
+  kind: Deployment
+  action: remove
+- version: extensions/v1beta1
+  kind: Deployment
+  removed-in: v1.16.0
+  component: k8s
+- version: apps/v1beta1
+  kind: Deployment
+  action: replace
+  component: k8s
+`,
+			targetVersions: map[string]string{"k8s": "v1.22.0"},
+			want: []string{
+				`test.yaml:15:11: invalid action "replace", must be one of [add override remove]`,
+			},
+		},
+		{
+			name: "invalid pattern and path",
+			data: `deprecated-versions:
+- version: /extensions/(v1beta1/
+  kind: Ingress
+  component: k8s
+  fields:
+  - path: ""
+`,
+			targetVersions: map[string]string{"k8s": "v1.22.0"},
+			want: []string{
+				"test.yaml:2:3: invalid pattern /extensions/(v1beta1/: error parsing regexp: missing closing ): `^(?:extensions/(v1beta1)$`",
+				`test.yaml:6:5: invalid field path ""`,
+			},
+		},
+		{
+			name: "platforms",
+			data: `platforms:
+  openshift:
+    "4.14":
+      k8s: v1.27.0
+    latest:
+      k8s: 1.28.0
+  EKS:
+    "1.27": v1.27.0
+`,
+			want: []string{
+				`test.yaml:5:5: openshift version "latest" is not a valid version`,
+				`test.yaml:6:12: openshift latest target version of k8s "1.28.0" is not valid semver (missing the leading 'v')`,
+				`test.yaml:7:3: platform EKS must be lower case`,
+				`test.yaml:8:13: EKS 1.27 must be a mapping of component to target version`,
+			},
+		},
+		{
+			name: "component probes",
+			data: `component-probes:
+  istio:
+  - selector: app=istiod
+    container: discovery
+  - namespace: istio-system
+    image: pilot
+  cert-manager:
+    selector: app=cert-manager
+`,
+			want: []string{
+				`test.yaml:5:5: component probe of istio is missing a selector`,
+				`test.yaml:6:5: unknown key "image", must be one of [namespace selector container]`,
+				`test.yaml:8:5: component-probes of cert-manager must be a list`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, err := range ValidateVersionFile("test.yaml", []byte(tt.data), tt.targetVersions) {
+				got = append(got, err.Error())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUnMarshalVersionsStrict(t *testing.T) {
+	data := []byte(`deprecated-versions:
+- version: extensions/v1beta1
+  kind: Ingress
+  removed-in: v1.22.0
+  component: k8s
+  replacement: networking.k8s.io/v1
+`)
+	_, _, err := UnMarshalVersions(data)
+	assert.NoError(t, err)
+
+	_, _, err = UnMarshalVersionsStrict("test.yaml", data, map[string]string{"k8s": "v1.22.0"})
+	assert.EqualError(t, err, `test.yaml:6:3: unknown key "replacement", must be one of [version kind deprecated-in removed-in replacement-api replacement-available-in component fields action]`)
+
+	versions, targets, err := UnMarshalVersionsStrict("test.yaml", data[:len(data)-len("  replacement: networking.k8s.io/v1\n")], map[string]string{"k8s": "v1.22.0"})
+	assert.NoError(t, err)
+	assert.Len(t, versions, 1)
+	assert.Empty(t, targets)
+}
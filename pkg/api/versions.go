@@ -36,7 +36,6 @@ import (
 	"os"
 	"text/tabwriter"
 
-	"golang.org/x/mod/semver"
 	"gopkg.in/yaml.v3"
 	"k8s.io/klog/v2"
 )
@@ -48,12 +47,23 @@ type Stub struct {
 	APIType    string   `json:"type,omitempty" yaml:"type,omitempty"`
 	Metadata   StubMeta `json:"metadata" yaml:"metadata"`
 	Items      []Stub   `json:"items" yaml:"items"`
+	// Document is the number of the document the stub was found in, counting from one,
+	// zero if it could not be determined
+	Document int `json:"-" yaml:"-"`
+	// Line is the line of the apiVersion key, zero if it could not be determined
+	Line int `json:"-" yaml:"-"`
+	// Column is the column of the apiVersion key, zero if it could not be determined
+	Column int `json:"-" yaml:"-"`
+	// IgnoreComment is whether the apiVersion line has a # lamb:ignore comment, see IgnoreComment
+	IgnoreComment bool `json:"-" yaml:"-"`
 }
 
 // StubMeta will catch kube resource metadata
 type StubMeta struct {
 	Name      string `json:"name" yaml:"name"`
 	Namespace string `json:"namespace" yaml:"namespace"`
+	// Annotations are used for IgnoreAnnotation
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
 }
 
 // Version is an apiVersion and a flag for deprecation
@@ -74,6 +84,15 @@ type Version struct {
 	ReplacementAvailableIn string `json:"replacement-available-in" yaml:"replacement-available-in"`
 	// Component is the component associated with this version
 	Component string `json:"component" yaml:"component"`
+	// Fields is an optional list of deprecated fields within objects of this version
+	Fields []Field `json:"fields,omitempty" yaml:"fields,omitempty"`
+	// Action is how an additional version is merged into the versions before it,
+	// one of add (the default), override or remove. See CombineAdditionalVersions.
+	Action string `json:"action,omitempty" yaml:"action,omitempty"`
+	// Origin is the versions file the version was last added or overridden by
+	Origin string `json:"-" yaml:"-"`
+	// overrides are the keys set in an override, see UnmarshalYAML
+	overrides []string
 }
 
 // VersionFile is a file with a list of deprecated versions
@@ -81,126 +100,258 @@ type VersionFile struct {
 	DeprecatedVersions []Version         `json:"deprecated-versions" yaml:"deprecated-versions"`
 	TargetVersions     map[string]string `json:"target-versions,omitempty" yaml:"target-versions,omitempty"`
 	TargetTyoes        map[string]string `json:"target-types,omitempty" yaml:"target-types,omitempty"`
+	Platforms          Platforms         `json:"platforms,omitempty" yaml:"platforms,omitempty"`
+	ComponentProbes    ComponentProbes   `json:"component-probes,omitempty" yaml:"component-probes,omitempty"`
 }
 
+// checkVersion returns the deprecated version matching the stub, or nil if there is none.
+// When several versions match, exact patterns win over wildcards and the most specific
+// wildcard wins after that, see isMoreSpecific.
 func (instance *Instance) checkVersion(stub *Stub) *Version {
-	for _, version := range instance.DeprecatedVersions {
-		// We allow empty kinds to deprecate whole APIs.
-		if version.Kind == "" || version.Kind == stub.Kind {
-			if version.Name == stub.APIVersion {
-				if version.Kind == "" {
-					version.Kind = stub.Kind
-				}
-				return &version
-			}
-		}
-	}
-	return nil
+	return instance.versionIndex().versionOf(stub)
 }
 
 // IsVersioned returns a version if the file data sent
 // can be unmarshaled into a stub and matches a known
-// version in the VersionList
+// version in the VersionList. Any deprecated fields of the
+// matched version that are set in the object are returned
+// as additional outputs with Field set, and every rule that
+// matches the object is returned as an output with Rule set.
+// If TrackObjects is set, objects without any of these are
+// returned as an output without a component, which FilterOutput
+// leaves out.
 func (instance *Instance) IsVersioned(data []byte) ([]*Output, error) {
 	var outputs []*Output
-	stubs, err := containsStub(data)
+	manifests, err := containsManifest(data)
 	if err != nil {
 		return nil, err
 	}
-	if len(stubs) > 0 {
-		for _, stub := range stubs {
+	if len(manifests) > 0 {
+		index := instance.versionIndex()
+		for _, m := range manifests {
+			stub := m.stub
 			var output Output
-			version := instance.checkVersion(stub)
+			version := index.versionOf(stub)
 			if version != nil {
+				fields := version.Fields
+				version.Fields = nil
 				output.Name = stub.Metadata.Name
 				output.Namespace = stub.Metadata.Namespace
 				output.APIVersion = version
-			} else {
-				continue
+				output.Document = stub.Document
+				output.Line = stub.Line
+				output.Column = stub.Column
+				output.ignore = ignoreRuleOf(stub)
+				outputs = append(outputs, &output)
+				for _, fieldOutput := range checkFields(m, version, fields) {
+					fieldOutput.ignore = output.ignore
+					outputs = append(outputs, fieldOutput)
+				}
+			}
+			ruleOutputs := instance.Rules.outputsOf(m)
+			outputs = append(outputs, ruleOutputs...)
+			if version == nil && len(ruleOutputs) == 0 && instance.TrackObjects {
+				outputs = append(outputs, &Output{
+					Name:       stub.Metadata.Name,
+					Namespace:  stub.Metadata.Namespace,
+					APIVersion: &Version{Name: stub.APIVersion, Kind: stub.Kind},
+					Document:   stub.Document,
+					Line:       stub.Line,
+					Column:     stub.Column,
+				})
 			}
-			outputs = append(outputs, &output)
 		}
 		return outputs, nil
 	}
 	return nil, nil
 }
 
+// manifest is a stub along with the yaml node it was decoded from,
+// so that rules can look at fields the stub does not capture
+type manifest struct {
+	stub *Stub
+	node *yaml.Node
+}
+
+func stubsOf(manifests []manifest) []*Stub {
+	var stubs []*Stub
+	for _, m := range manifests {
+		stubs = append(stubs, m.stub)
+	}
+	return stubs
+}
+
 // containsStub checks to see if a []byte has a stub in it
 func containsStub(data []byte) ([]*Stub, error) {
+	manifests, err := containsManifest(data)
+	return stubsOf(manifests), err
+}
+
+// containsManifest checks to see if a []byte has a stub in it and keeps the decoded nodes
+func containsManifest(data []byte) ([]manifest, error) {
 	klog.V(10).Infof("\n%s", string(data))
-	stub, err := jsonToStub(data)
+	manifests, err := jsonToManifest(data)
 	if err != nil {
 		klog.V(8).Infof("invalid json: %s, trying yaml", err.Error())
 	} else {
-		return stub, nil
+		return manifests, nil
 	}
-	stub, err = yamlToStub(data)
+	manifests, err = yamlToManifest(data)
 	if err != nil {
 		klog.V(8).Infof("invalid yaml: %s", err.Error())
 	} else {
-		return stub, nil
+		return manifests, nil
 	}
 	return nil, err
 }
 
 func jsonToStub(data []byte) ([]*Stub, error) {
-	var stubs []*Stub
+	manifests, err := jsonToManifest(data)
+	return stubsOf(manifests), err
+}
+
+func jsonToManifest(data []byte) ([]manifest, error) {
+	var manifests []manifest
 	stub := &Stub{}
 	err := json.Unmarshal(data, stub)
 	if err != nil {
 		return nil, err
 	}
-	expandList(&stubs, stub)
-	return stubs, nil
+	// JSON is a subset of YAML, so the node tree gives us key positions
+	// without a separate offset-tracking decoder
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err == nil {
+		setStubPosition(stub, 1, &node)
+	} else {
+		klog.V(8).Infof("could not determine positions in json: %s", err.Error())
+	}
+	expandList(&manifests, stub, &node)
+	return manifests, nil
 }
 
 func yamlToStub(data []byte) ([]*Stub, error) {
+	manifests, err := yamlToManifest(data)
+	return stubsOf(manifests), err
+}
+
+func yamlToManifest(data []byte) ([]manifest, error) {
 	decoder := yaml.NewDecoder(bytes.NewReader(data))
-	var stubs []*Stub
+	var manifests []manifest
 	var tError *yaml.TypeError
 	var errs []error
-	for {
-		stub := &Stub{}
-		err := decoder.Decode(stub)
+	for document := 1; ; document++ {
+		node := &yaml.Node{}
+		err := decoder.Decode(node)
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
+			return manifests, err
+		}
+		stub := &Stub{}
+		err = node.Decode(stub)
+		if err != nil {
 			if errors.As(err, &tError) {
 				klog.V(2).Infof("skipping for invalid yaml in manifest: %s", err)
 				errs = append(errs, err)
 				continue
 			}
-			return stubs, err
+			return manifests, err
 		}
-		expandList(&stubs, stub)
+		setStubPosition(stub, document, node)
+		expandList(&manifests, stub, node)
 	}
-	if stubs == nil && len(errs) > 0 {
+	if manifests == nil && len(errs) > 0 {
 		return nil, fmt.Errorf("one or more errors parsing yaml resulted in no versions found: %v", errs)
 	}
-	return stubs, nil
+	return manifests, nil
+}
+
+// setStubPosition records the document number and the position of the apiVersion key
+// for the stub and any list items it contains
+func setStubPosition(stub *Stub, document int, node *yaml.Node) {
+	node = documentContent(node)
+	stub.Document = document
+	if key := mappingKey(node, "apiVersion"); key != nil {
+		stub.Line = key.Line
+		stub.Column = key.Column
+		stub.IgnoreComment = hasIgnoreComment(key, mappingValue(node, "apiVersion"))
+	}
+	items := mappingValue(node, "items")
+	if items == nil || items.Kind != yaml.SequenceNode {
+		return
+	}
+	for i := range stub.Items {
+		if i >= len(items.Content) {
+			break
+		}
+		setStubPosition(&stub.Items[i], document, items.Content[i])
+	}
+}
+
+// documentContent returns the root of a document node, or the node itself for any other kind
+func documentContent(node *yaml.Node) *yaml.Node {
+	if node != nil && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		return node.Content[0]
+	}
+	return node
+}
+
+// mappingKey returns the key node with the given name if node is a mapping
+func mappingKey(node *yaml.Node, name string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == name {
+			return node.Content[i]
+		}
+	}
+	return nil
+}
+
+// mappingValue returns the value node for the given key if node is a mapping
+func mappingValue(node *yaml.Node, name string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == name {
+			return node.Content[i+1]
+		}
+	}
+	return nil
 }
 
 // expandList checks if we have a List manifest.
 // If it is the case, the manifests inside are expanded, otherwise we just return the single manifest
-func expandList(stubs *[]*Stub, currentStub *Stub) {
+func expandList(manifests *[]manifest, currentStub *Stub, node *yaml.Node) {
+	node = documentContent(node)
 	if len(currentStub.Items) > 0 {
 		klog.V(5).Infof("found a list with %d items, attempting to expand", len(currentStub.Items))
-		for _, stub := range currentStub.Items {
+		var itemNodes []*yaml.Node
+		if items := mappingValue(node, "items"); items != nil && items.Kind == yaml.SequenceNode {
+			itemNodes = items.Content
+		}
+		for i, stub := range currentStub.Items {
 			currentItem := stub
-			*stubs = append(*stubs, &currentItem)
+			var itemNode *yaml.Node
+			if i < len(itemNodes) {
+				itemNode = itemNodes[i]
+			}
+			*manifests = append(*manifests, manifest{stub: &currentItem, node: itemNode})
 		}
 	} else {
-		*stubs = append(*stubs, currentStub)
+		*manifests = append(*manifests, manifest{stub: currentStub, node: node})
 	}
 }
 
 // IsDeprecatedIn returns true if the version is deprecated in the applicable targetVersion
-// Will return false if the targetVersion passed is not a valid semver string
+// Will return false if the targetVersion passed is not a valid version, see NormalizeVersion
 func (v *Version) isDeprecatedIn(targetVersions map[string]string) bool {
 	for component, targetVersion := range targetVersions {
-		if !semver.IsValid(targetVersion) {
-			klog.V(3).Infof("targetVersion %s for %s is not valid semVer", targetVersion, component)
+		if !IsValidVersion(targetVersion) {
+			klog.V(3).Infof("targetVersion %s for %s is not a valid version", targetVersion, component)
 			return false
 		}
 	}
@@ -215,16 +366,16 @@ func (v *Version) isDeprecatedIn(targetVersions map[string]string) bool {
 		return false
 	}
 
-	comparison := semver.Compare(targetVersion, v.DeprecatedIn)
+	comparison := compareVersions(targetVersion, v.DeprecatedIn)
 	return comparison >= 0
 }
 
 // IsRemovedIn returns true if the version is deprecated in the applicable targetVersion
-// Will return false if the targetVersion passed is not a valid semver string
+// Will return false if the targetVersion passed is not a valid version, see NormalizeVersion
 func (v *Version) isRemovedIn(targetVersions map[string]string) bool {
 	for component, targetVersion := range targetVersions {
-		if !semver.IsValid(targetVersion) {
-			klog.V(3).Infof("targetVersion %s for %s is not valid semVer", targetVersion, component)
+		if !IsValidVersion(targetVersion) {
+			klog.V(3).Infof("targetVersion %s for %s is not a valid version", targetVersion, component)
 			return false
 		}
 	}
@@ -239,16 +390,16 @@ func (v *Version) isRemovedIn(targetVersions map[string]string) bool {
 		return false
 	}
 
-	comparison := semver.Compare(targetVersion, v.RemovedIn)
+	comparison := compareVersions(targetVersion, v.RemovedIn)
 	return comparison >= 0
 }
 
 // isReplacementAvailableIn returns true if the replacement api is available in the applicable targetVersion
-// Will return false if the targetVersion passed is not a valid semver string
+// Will return false if the targetVersion passed is not a valid version, see NormalizeVersion
 func (v *Version) isReplacementAvailableIn(targetVersions map[string]string) bool {
 	for component, targetVersion := range targetVersions {
-		if !semver.IsValid(targetVersion) {
-			klog.V(3).Infof("targetVersion %s for %s is not valid semVer", targetVersion, component)
+		if !IsValidVersion(targetVersion) {
+			klog.V(3).Infof("targetVersion %s for %s is not a valid version", targetVersion, component)
 			return false
 		}
 	}
@@ -263,7 +414,7 @@ func (v *Version) isReplacementAvailableIn(targetVersions map[string]string) boo
 		return false
 	}
 
-	comparison := semver.Compare(targetVersion, v.ReplacementAvailableIn)
+	comparison := compareVersions(targetVersion, v.ReplacementAvailableIn)
 	return comparison >= 0
 }
 
@@ -291,7 +442,20 @@ func (instance *Instance) PrintVersionList(outputFormat string) error {
 			DeprecatedVersions: instance.DeprecatedVersions,
 			TargetVersions:     instance.TargetVersions,
 		}
-		data, err := yaml.Marshal(versionFile)
+		var node yaml.Node
+		err := node.Encode(versionFile)
+		if err != nil {
+			return err
+		}
+		// annotate each version with its origin without changing the schema
+		if versions := mappingValue(&node, "deprecated-versions"); versions != nil {
+			for i, item := range versions.Content {
+				if i < len(instance.DeprecatedVersions) && instance.DeprecatedVersions[i].Origin != "" {
+					item.HeadComment = "origin: " + instance.DeprecatedVersions[i].Origin
+				}
+			}
+		}
+		data, err := yaml.Marshal(&node)
 		if err != nil {
 			return err
 		}
@@ -309,7 +473,7 @@ func (instance *Instance) printVersionsTabular() error {
 	w.Init(os.Stdout, 0, 15, 2, padChar, 0)
 
 	if !instance.NoHeaders {
-		fmt.Fprintln(w, "KIND\t NAME\t DEPRECATED IN\t REMOVED IN\t REPLACEMENT\t REPL AVAIL IN\t COMPONENT\t")
+		fmt.Fprintln(w, "KIND\t NAME\t DEPRECATED IN\t REMOVED IN\t REPLACEMENT\t REPL AVAIL IN\t COMPONENT\t MATCH\t")
 	}
 
 	for _, version := range instance.DeprecatedVersions {
@@ -332,7 +496,12 @@ func (instance *Instance) printVersionsTabular() error {
 			replacementAvailableIn = "n/a"
 		}
 
-		_, _ = fmt.Fprintf(w, "%s\t %s\t %s\t %s\t %s\t %s\t %s\t\n", version.Kind, version.Name, deprecatedIn, removedIn, replacementAPI, replacementAvailableIn, version.Component)
+		_, _ = fmt.Fprintf(w, "%s\t %s\t %s\t %s\t %s\t %s\t %s\t %s\t\n", version.Kind, version.Name, deprecatedIn, removedIn, replacementAPI, replacementAvailableIn, version.Component, version.matchType())
+
+		for _, field := range version.Fields {
+			fieldVersion := field.version(&version)
+			_, _ = fmt.Fprintf(w, "%s\t %s\t %s\t %s\t %s\t %s\t %s\t %s\t\n", version.Kind, version.Name+" "+field.Path, orNotApplicable(fieldVersion.DeprecatedIn), orNotApplicable(fieldVersion.RemovedIn), orNotApplicable(fieldVersion.ReplacementAPI), orNotApplicable(fieldVersion.ReplacementAvailableIn), fieldVersion.Component, version.matchType())
+		}
 	}
 	err := w.Flush()
 	if err != nil {
@@ -341,18 +510,40 @@ func (instance *Instance) printVersionsTabular() error {
 	return nil
 }
 
+func orNotApplicable(s string) string {
+	if s == "" {
+		return "n/a"
+	}
+	return s
+}
+
 // UnMarshalVersions reads data from a versions file and returns the versions
 // If included, it will also return the map of targetVersions
 func UnMarshalVersions(data []byte) ([]Version, map[string]string, error) {
-	versionFile := &VersionFile{}
-	err := yaml.Unmarshal(data, versionFile)
+	versionFile, err := UnMarshalVersionFile(data)
 	if err != nil {
-		return nil, nil, fmt.Errorf("could not unmarshal versions file from data: %s", err.Error())
+		return nil, nil, err
 	}
 	return versionFile.DeprecatedVersions, versionFile.TargetVersions, nil
 
 }
 
+// UnMarshalVersionFile reads data from a versions file
+func UnMarshalVersionFile(data []byte) (*VersionFile, error) {
+	versionFile := &VersionFile{}
+	err := yaml.Unmarshal(data, versionFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal versions file from data: %s", err.Error())
+	}
+	for _, version := range versionFile.DeprecatedVersions {
+		err := version.validatePatterns()
+		if err != nil {
+			return nil, fmt.Errorf("invalid deprecated version %s %s: %s", version.Kind, version.Name, err.Error())
+		}
+	}
+	return versionFile, nil
+}
+
 // GetDefaultVersionList gets the default versions from the versions.yaml file
 func GetDefaultVersionList(versionFileData []byte) ([]Version, map[string]string, error) {
 	defaultVersions, defaultTargetVersions, err := UnMarshalVersions(versionFileData)
@@ -362,27 +553,51 @@ func GetDefaultVersionList(versionFileData []byte) ([]Version, map[string]string
 	return defaultVersions, defaultTargetVersions, nil
 }
 
-// CombineAdditionalVersions adds additional versions into the defaults. If the additional versions
-// contain any that already exist in the defaults, return an error
+// CombineAdditionalVersions merges additional versions into the defaults in order, according to
+// the Action of each additional version. Versions that are added must not already exist, versions
+// that are overridden or removed must. The defaults are not modified.
 func CombineAdditionalVersions(additional []Version, defaults []Version) ([]Version, error) {
-	returnList := defaults
+	returnList := make([]Version, len(defaults))
+	copy(returnList, defaults)
 	for _, version := range additional {
 		klog.V(3).Infof("attempting to combine into defaults: %v", version)
-		if version.isContainedIn(defaults) {
-			return nil, fmt.Errorf("duplicate cannot be added to defaults: %s %s", version.Kind, version.Name)
+		index := version.indexIn(returnList)
+		switch version.Action {
+		case "", VersionActionAdd:
+			if index >= 0 {
+				return nil, fmt.Errorf("duplicate cannot be added to defaults: %s %s", version.Kind, version.Name)
+			}
+			version.Action = ""
+			returnList = append(returnList, version)
+		case VersionActionOverride:
+			if index < 0 {
+				return nil, fmt.Errorf("cannot override %s %s, it is not in the versions before it", version.Kind, version.Name)
+			}
+			returnList[index] = returnList[index].overriddenBy(version)
+		case VersionActionRemove:
+			if index < 0 {
+				return nil, fmt.Errorf("cannot remove %s %s, it is not in the versions before it", version.Kind, version.Name)
+			}
+			returnList = append(returnList[:index], returnList[index+1:]...)
+		default:
+			return nil, fmt.Errorf("invalid action %q for %s %s, must be one of %v", version.Action, version.Kind, version.Name, versionActions)
 		}
-		returnList = append(returnList, version)
 	}
 	return returnList, nil
 }
 
 func (v Version) isContainedIn(versionList []Version) bool {
-	for _, version := range versionList {
+	return v.indexIn(versionList) >= 0
+}
+
+// indexIn returns the index of the duplicate of v in versionList, or -1
+func (v Version) indexIn(versionList []Version) int {
+	for i, version := range versionList {
 		if isDuplicate(v, version) {
-			return true
+			return i
 		}
 	}
-	return false
+	return -1
 }
 
 func isDuplicate(a Version, b Version) bool {
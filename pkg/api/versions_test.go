@@ -29,7 +29,9 @@
 package api
 
 import (
+	"bytes"
 	_ "embed"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -83,7 +85,7 @@ func Test_jsonToStub(t *testing.T) {
 		{
 			name:    "json not stub",
 			data:    []byte("{}"),
-			want:    []*Stub{{}},
+			want:    []*Stub{{Document: 1}},
 			wantErr: false,
 		},
 		{
@@ -95,13 +97,13 @@ func Test_jsonToStub(t *testing.T) {
 		{
 			name:    "json is stub",
 			data:    []byte(`{"kind": "foo", "apiVersion": "bar"}`),
-			want:    []*Stub{{Kind: "foo", APIVersion: "bar"}},
+			want:    []*Stub{{Kind: "foo", APIVersion: "bar", Document: 1, Line: 1, Column: 17}},
 			wantErr: false,
 		},
 		{
 			name:    "json list is multiple stubs",
-			data:    []byte(`{"kind": "List", "apiVersion": "v1", "items": [{"kind": "foo", "apiVersion": "bar"},{"kind": "bar", "apiVersion": "foo"}]}`),
-			want:    []*Stub{{Kind: "foo", APIVersion: "bar"}, {Kind: "bar", APIVersion: "foo"}},
+			data:    []byte("{\"kind\": \"List\", \"apiVersion\": \"v1\", \"items\": [\n{\"kind\": \"foo\", \"apiVersion\": \"bar\"},\n{\"kind\": \"bar\", \"apiVersion\": \"foo\"}]}"),
+			want:    []*Stub{{Kind: "foo", APIVersion: "bar", Document: 1, Line: 2, Column: 17}, {Kind: "bar", APIVersion: "foo", Document: 1, Line: 3, Column: 17}},
 			wantErr: false,
 		},
 	}
@@ -129,7 +131,7 @@ func Test_yamlToStub(t *testing.T) {
 		{
 			name:    "yaml not stub",
 			data:    []byte("foo: bar"),
-			want:    []*Stub{{}},
+			want:    []*Stub{{Document: 1}},
 			wantErr: false,
 		},
 		{
@@ -141,13 +143,19 @@ func Test_yamlToStub(t *testing.T) {
 		{
 			name:    "yaml is stub",
 			data:    []byte("kind: foo\napiVersion: bar"),
-			want:    []*Stub{{Kind: "foo", APIVersion: "bar"}},
+			want:    []*Stub{{Kind: "foo", APIVersion: "bar", Document: 1, Line: 2, Column: 1}},
 			wantErr: false,
 		},
 		{
 			name:    "yaml list is multiple stubs",
 			data:    []byte("kind: List\napiVersion: v1\nitems:\n- kind: foo\n  apiVersion: bar\n- kind: bar\n  apiVersion: foo"),
-			want:    []*Stub{{Kind: "foo", APIVersion: "bar"}, {Kind: "bar", APIVersion: "foo"}},
+			want:    []*Stub{{Kind: "foo", APIVersion: "bar", Document: 1, Line: 5, Column: 3}, {Kind: "bar", APIVersion: "foo", Document: 1, Line: 7, Column: 3}},
+			wantErr: false,
+		},
+		{
+			name:    "yaml multiple documents",
+			data:    []byte("kind: foo\napiVersion: bar\n---\n# comment\nkind: bar\napiVersion: foo"),
+			want:    []*Stub{{Kind: "foo", APIVersion: "bar", Document: 1, Line: 2, Column: 1}, {Kind: "bar", APIVersion: "foo", Document: 2, Line: 6, Column: 1}},
 			wantErr: false,
 		},
 	}
@@ -175,7 +183,7 @@ func Test_containsStub(t *testing.T) {
 		{
 			name:    "yaml not stub",
 			data:    []byte("foo: bar"),
-			want:    []*Stub{{}},
+			want:    []*Stub{{Document: 1}},
 			wantErr: false,
 		},
 		{
@@ -187,13 +195,13 @@ func Test_containsStub(t *testing.T) {
 		{
 			name:    "yaml is stub",
 			data:    []byte("kind: foo\napiVersion: bar"),
-			want:    []*Stub{{Kind: "foo", APIVersion: "bar"}},
+			want:    []*Stub{{Kind: "foo", APIVersion: "bar", Document: 1, Line: 2, Column: 1}},
 			wantErr: false,
 		},
 		{
 			name:    "json not stub",
 			data:    []byte("{}"),
-			want:    []*Stub{{}},
+			want:    []*Stub{{Document: 1}},
 			wantErr: false,
 		},
 		{
@@ -211,7 +219,7 @@ func Test_containsStub(t *testing.T) {
 		{
 			name:    "json is stub",
 			data:    []byte(`{"kind": "foo", "apiVersion": "bar"}`),
-			want:    []*Stub{{Kind: "foo", APIVersion: "bar"}},
+			want:    []*Stub{{Kind: "foo", APIVersion: "bar", Document: 1, Line: 1, Column: 17}},
 			wantErr: false,
 		},
 	}
@@ -251,13 +259,13 @@ func Test_IsVersioned(t *testing.T) {
 		{
 			name:    "yaml has version",
 			data:    []byte("kind: Deployment\napiVersion: extensions/v1beta1"),
-			want:    []*Output{{APIVersion: &testVersionDeployment}},
+			want:    []*Output{{APIVersion: &testVersionDeployment, Document: 1, Line: 2, Column: 1}},
 			wantErr: false,
 		},
 		{
 			name:    "yaml list has version",
 			data:    []byte("kind: List\napiVersion: v1\nitems:\n- kind: Deployment\n  apiVersion: extensions/v1beta1"),
-			want:    []*Output{{APIVersion: &testVersionDeployment}},
+			want:    []*Output{{APIVersion: &testVersionDeployment, Document: 1, Line: 5, Column: 3}},
 			wantErr: false,
 		},
 		{
@@ -281,13 +289,13 @@ func Test_IsVersioned(t *testing.T) {
 		{
 			name:    "json has version",
 			data:    []byte(`{"kind": "Deployment", "apiVersion": "extensions/v1beta1"}`),
-			want:    []*Output{{APIVersion: &testVersionDeployment}},
+			want:    []*Output{{APIVersion: &testVersionDeployment, Document: 1, Line: 1, Column: 24}},
 			wantErr: false,
 		},
 		{
 			name:    "json list has version",
 			data:    []byte(`{"kind": "List", "apiVersion": "v1", "items": [{"kind": "Deployment", "apiVersion": "extensions/v1beta1"}]}`),
-			want:    []*Output{{APIVersion: &testVersionDeployment}},
+			want:    []*Output{{APIVersion: &testVersionDeployment, Document: 1, Line: 1, Column: 71}},
 			wantErr: false,
 		},
 		{
@@ -494,9 +502,9 @@ func ExampleInstance_printVersionsTabular() {
 	_ = instance.printVersionsTabular()
 
 	// Output:
-	// KIND-------- NAME---------------- DEPRECATED IN-- REMOVED IN-- REPLACEMENT-- REPL AVAIL IN-- COMPONENT--
-	// Deployment-- extensions/v1beta1-- v1.9.0--------- v1.0.0----- apps/v1------ v1.0.0-------- k8s--------
-	// testkind---- testname------------ n/a------------ n/a--------- n/a---------- n/a------------ custom-----
+	// KIND-------- NAME---------------- DEPRECATED IN-- REMOVED IN-- REPLACEMENT-- REPL AVAIL IN-- COMPONENT-- MATCH--
+	// Deployment-- extensions/v1beta1-- v1.9.0--------- v1.0.0------ apps/v1------ v1.0.0--------- k8s-------- exact--
+	// testkind---- testname------------ n/a------------ n/a--------- n/a---------- n/a------------ custom----- exact--
 }
 
 func ExampleInstance_printVersionsTabular_noHeaders() {
@@ -510,8 +518,23 @@ func ExampleInstance_printVersionsTabular_noHeaders() {
 	_ = instance.printVersionsTabular()
 
 	// Output:
-	// Deployment-- extensions/v1beta1-- v1.0.0-- v1.0.0-- apps/v1-- v1.0.0-- k8s-----
-	// testkind---- testname------------ n/a----- n/a------ n/a------ n/a------ custom--
+	// Deployment-- extensions/v1beta1-- v1.9.0-- v1.0.0-- apps/v1-- v1.0.0-- k8s----- exact--
+	// testkind---- testname------------ n/a----- n/a----- n/a------ n/a----- custom-- exact--
+}
+
+func ExampleInstance_printVersionsTabular_patterns() {
+	instance := Instance{
+		DeprecatedVersions: []Version{
+			{Kind: "", Name: "*.internal.example.com/v1alpha1", DeprecatedIn: "v1.0.0", Component: "internal"},
+			{Kind: "/Flow.*/", Name: "flowcontrol.apiserver.k8s.io/v1beta1", DeprecatedIn: "v1.0.0", Component: "internal"},
+		},
+	}
+	_ = instance.printVersionsTabular()
+
+	// Output:
+	// KIND------ NAME---------------------------------- DEPRECATED IN-- REMOVED IN-- REPLACEMENT-- REPL AVAIL IN-- COMPONENT-- MATCH-------------------
+	// ---------- *.internal.example.com/v1alpha1------- v1.0.0--------- n/a--------- n/a---------- n/a------------ internal--- version glob, kind any--
+	// /Flow.*/-- flowcontrol.apiserver.k8s.io/v1beta1-- v1.0.0--------- n/a--------- n/a---------- n/a------------ internal--- kind regex--------------
 }
 
 func ExampleInstance_PrintVersionList_json() {
@@ -541,6 +564,26 @@ func ExampleInstance_PrintVersionList_yaml() {
 	//       component: k8s
 }
 
+func ExampleInstance_PrintVersionList_yamlOrigin() {
+	overridden := testVersionDeployment
+	overridden.Origin = "custom/versions.yaml"
+	instance := Instance{
+		DeprecatedVersions: []Version{overridden},
+	}
+	_ = instance.PrintVersionList("yaml")
+
+	// Output:
+	// deprecated-versions:
+	//     # origin: custom/versions.yaml
+	//     - version: extensions/v1beta1
+	//       kind: Deployment
+	//       deprecated-in: v1.9.0
+	//       removed-in: v1.0.0
+	//       replacement-api: apps/v1
+	//       replacement-available-in: v1.0.0
+	//       component: k8s
+}
+
 func ExampleInstance_PrintVersionList_normal() {
 	instance := Instance{
 		DeprecatedVersions: []Version{testVersionDeployment},
@@ -548,8 +591,8 @@ func ExampleInstance_PrintVersionList_normal() {
 	_ = instance.PrintVersionList("normal")
 
 	// Output:
-	// KIND-------- NAME---------------- DEPRECATED IN-- REMOVED IN-- REPLACEMENT-- REPL AVAIL IN-- COMPONENT--
-	// Deployment-- extensions/v1beta1-- v1.0.0--------- v1.0.0----- apps/v1------ v1.0.0-------- k8s--------
+	// KIND-------- NAME---------------- DEPRECATED IN-- REMOVED IN-- REPLACEMENT-- REPL AVAIL IN-- COMPONENT-- MATCH--
+	// Deployment-- extensions/v1beta1-- v1.9.0--------- v1.0.0------ apps/v1------ v1.0.0--------- k8s-------- exact--
 }
 
 func ExampleInstance_PrintVersionList_wide() {
@@ -559,8 +602,8 @@ func ExampleInstance_PrintVersionList_wide() {
 	_ = instance.PrintVersionList("wide")
 
 	// Output:
-	// KIND-------- NAME---------------- DEPRECATED IN-- REMOVED IN-- REPLACEMENT-- REPL AVAIL IN-- COMPONENT--
-	// Deployment-- extensions/v1beta1-- v1.9.0--------- v1.0.0----- apps/v1------ v1.0.0-------- k8s--------
+	// KIND-------- NAME---------------- DEPRECATED IN-- REMOVED IN-- REPLACEMENT-- REPL AVAIL IN-- COMPONENT-- MATCH--
+	// Deployment-- extensions/v1beta1-- v1.9.0--------- v1.0.0------ apps/v1------ v1.0.0--------- k8s-------- exact--
 }
 
 func ExampleInstance_PrintVersionList_badformat() {
@@ -677,6 +720,69 @@ func TestCombineAdditionalVersions(t *testing.T) {
 				{Kind: "Deployment", Name: "extensions/v1beta1"},
 			},
 		},
+		{
+			name: "override replaces the version",
+			args: args{
+				additional: []Version{
+					{Kind: "Deployment", Name: "extensions/v1beta1", RemovedIn: "v1.18.0", Action: VersionActionOverride},
+				},
+				defaults: []Version{
+					{Kind: "Deployment", Name: "apps/v1"},
+					{Kind: "Deployment", Name: "extensions/v1beta1", DeprecatedIn: "v1.9.0", RemovedIn: "v1.16.0", Origin: DefaultVersionsOrigin},
+				},
+			},
+			want: []Version{
+				{Kind: "Deployment", Name: "apps/v1"},
+				{Kind: "Deployment", Name: "extensions/v1beta1", RemovedIn: "v1.18.0", Origin: DefaultVersionsOrigin},
+			},
+		},
+		{
+			name: "remove",
+			args: args{
+				additional: []Version{
+					{Kind: "Deployment", Name: "apps/v1", Action: VersionActionRemove},
+					{Kind: "Deployment", Name: "apps/v1", Action: VersionActionAdd},
+				},
+				defaults: []Version{
+					{Kind: "Deployment", Name: "apps/v1", RemovedIn: "v1.16.0"},
+					{Kind: "Deployment", Name: "extensions/v1beta1"},
+				},
+			},
+			want: []Version{
+				{Kind: "Deployment", Name: "extensions/v1beta1"},
+				{Kind: "Deployment", Name: "apps/v1"},
+			},
+		},
+		{
+			name: "error overriding a missing version",
+			args: args{
+				additional: []Version{
+					{Kind: "Deployment", Name: "apps/v1", Action: VersionActionOverride},
+				},
+			},
+			wantErr:  true,
+			errorMsg: "cannot override Deployment apps/v1, it is not in the versions before it",
+		},
+		{
+			name: "error removing a missing version",
+			args: args{
+				additional: []Version{
+					{Kind: "Deployment", Name: "apps/v1", Action: VersionActionRemove},
+				},
+			},
+			wantErr:  true,
+			errorMsg: "cannot remove Deployment apps/v1, it is not in the versions before it",
+		},
+		{
+			name: "error with an invalid action",
+			args: args{
+				additional: []Version{
+					{Kind: "Deployment", Name: "apps/v1", Action: "patch"},
+				},
+			},
+			wantErr:  true,
+			errorMsg: `invalid action "patch" for Deployment apps/v1, must be one of [add override remove]`,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -760,11 +866,176 @@ func TestInstance_checkVersion(t *testing.T) {
 				Component: "cert-manager",
 			},
 		},
+		{
+			name: "no match",
+			instance: &Instance{
+				DeprecatedVersions: []Version{
+					{Kind: "Deployment", Name: "extensions/v1beta1", Component: "k8s"},
+				},
+			},
+			stub: &Stub{Kind: "Deployment", APIVersion: "apps/v1"},
+			want: nil,
+		},
+		{
+			name: "glob version",
+			instance: &Instance{
+				DeprecatedVersions: []Version{
+					{Kind: "", Name: "*.internal.example.com/v1alpha1", Component: "internal"},
+				},
+			},
+			stub: &Stub{Kind: "Widget", APIVersion: "widgets.internal.example.com/v1alpha1"},
+			want: &Version{Name: "widgets.internal.example.com/v1alpha1", Kind: "Widget", Component: "internal"},
+		},
+		{
+			name: "regex kind",
+			instance: &Instance{
+				DeprecatedVersions: []Version{
+					{Kind: "/(Flow|PriorityLevel).*/", Name: "flowcontrol.apiserver.k8s.io/*", Component: "k8s"},
+				},
+			},
+			stub: &Stub{Kind: "PriorityLevelConfiguration", APIVersion: "flowcontrol.apiserver.k8s.io/v1beta2"},
+			want: &Version{Name: "flowcontrol.apiserver.k8s.io/v1beta2", Kind: "PriorityLevelConfiguration", Component: "k8s"},
+		},
+		{
+			name: "exact wins over wildcard",
+			instance: &Instance{
+				DeprecatedVersions: []Version{
+					{Kind: "", Name: "flowcontrol.apiserver.k8s.io/*", Component: "glob"},
+					{Kind: "FlowSchema", Name: "flowcontrol.apiserver.k8s.io/v1beta2", Component: "exact"},
+					{Kind: "", Name: "flowcontrol.apiserver.k8s.io/v1beta2", Component: "any kind"},
+				},
+			},
+			stub: &Stub{Kind: "FlowSchema", APIVersion: "flowcontrol.apiserver.k8s.io/v1beta2"},
+			want: &Version{Name: "flowcontrol.apiserver.k8s.io/v1beta2", Kind: "FlowSchema", Component: "exact"},
+		},
+		{
+			name: "most specific wildcard wins",
+			instance: &Instance{
+				DeprecatedVersions: []Version{
+					{Kind: "", Name: "/.*example\\.com/v1alpha1/", Component: "regex"},
+					{Kind: "", Name: "*.example.com/*", Component: "short glob"},
+					{Kind: "", Name: "*.internal.example.com/*", Component: "long glob"},
+				},
+			},
+			stub: &Stub{Kind: "Widget", APIVersion: "widgets.internal.example.com/v1alpha1"},
+			want: &Version{Name: "widgets.internal.example.com/v1alpha1", Kind: "Widget", Component: "long glob"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			got := tt.instance.checkVersion(tt.stub)
 			assert.EqualValues(t, tt.want, got)
+
+			tt.instance.BuildIndex()
+			got = tt.instance.checkVersion(tt.stub)
+			assert.EqualValues(t, tt.want, got)
+		})
+	}
+}
+
+func TestInstance_BuildIndex(t *testing.T) {
+	instance := &Instance{
+		DeprecatedVersions: []Version{
+			{Kind: "Deployment", Name: "extensions/v1beta1", Component: "first"},
+			{Kind: "Deployment", Name: "extensions/v1beta1", Component: "second"},
+		},
+	}
+	instance.BuildIndex()
+
+	// the index keeps its own copy of the versions
+	instance.DeprecatedVersions[0].Component = "changed"
+	got := instance.checkVersion(&Stub{Kind: "Deployment", APIVersion: "extensions/v1beta1"})
+	assert.Equal(t, "first", got.Component)
+
+	// returned versions are copies
+	got.Component = "modified"
+	got = instance.checkVersion(&Stub{Kind: "Deployment", APIVersion: "extensions/v1beta1"})
+	assert.Equal(t, "first", got.Component)
+}
+
+// benchmarkInstance returns an instance with rules exact rules spread over groups,
+// plus a handful of kind-agnostic and wildcard rules
+func benchmarkInstance(rules int) *Instance {
+	instance := &Instance{
+		TargetVersions: map[string]string{"k8s": "v1.22.0"},
+		Components:     []string{"k8s"},
+	}
+	for i := 0; i < rules; i++ {
+		instance.DeprecatedVersions = append(instance.DeprecatedVersions, Version{
+			Name:         fmt.Sprintf("group%d.example.com/v1beta1", i),
+			Kind:         fmt.Sprintf("Kind%d", i),
+			DeprecatedIn: "v1.16.0",
+			Component:    "k8s",
+		})
+	}
+	instance.DeprecatedVersions = append(instance.DeprecatedVersions,
+		Version{Name: "cert-manager.k8s.io/v1alpha1", DeprecatedIn: "v1.16.0", Component: "k8s"},
+		Version{Name: "*.internal.example.com/v1alpha1", DeprecatedIn: "v1.16.0", Component: "k8s"},
+		Version{Name: "flowcontrol.apiserver.k8s.io/*", Kind: "/Flow.*/", DeprecatedIn: "v1.16.0", Component: "k8s"},
+	)
+	return instance
+}
+
+// benchmarkManifests returns a multi-document yaml stream of documents objects,
+// a mix of deprecated and current apiVersions
+func benchmarkManifests(documents int, rules int) []byte {
+	var data bytes.Buffer
+	for i := 0; i < documents; i++ {
+		apiVersion, kind := "apps/v1", "Deployment"
+		switch i % 4 {
+		case 1:
+			apiVersion, kind = fmt.Sprintf("group%d.example.com/v1beta1", i%rules), fmt.Sprintf("Kind%d", i%rules)
+		case 2:
+			apiVersion, kind = "widgets.internal.example.com/v1alpha1", "Widget"
+		case 3:
+			apiVersion, kind = "cert-manager.k8s.io/v1alpha1", "Certificate"
+		}
+		fmt.Fprintf(&data, "---\napiVersion: %s\nkind: %s\nmetadata:\n  name: object-%d\n  namespace: default\n", apiVersion, kind, i)
+	}
+	return data.Bytes()
+}
+
+func BenchmarkInstance_checkVersion(b *testing.B) {
+	for _, rules := range []int{10, 500, 5000} {
+		instance := benchmarkInstance(rules)
+		instance.BuildIndex()
+		stubs := []*Stub{
+			{Kind: "Deployment", APIVersion: "apps/v1"},
+			{Kind: fmt.Sprintf("Kind%d", rules/2), APIVersion: fmt.Sprintf("group%d.example.com/v1beta1", rules/2)},
+			{Kind: "Certificate", APIVersion: "cert-manager.k8s.io/v1alpha1"},
+			{Kind: "Widget", APIVersion: "widgets.internal.example.com/v1alpha1"},
+			{Kind: "FlowSchema", APIVersion: "flowcontrol.apiserver.k8s.io/v1beta2"},
+		}
+		b.Run(fmt.Sprintf("rules=%d", rules), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = instance.checkVersion(stubs[i%len(stubs)])
+			}
+		})
+	}
+}
+
+func BenchmarkInstance_IsVersioned(b *testing.B) {
+	for _, size := range []struct {
+		rules     int
+		documents int
+	}{
+		{rules: 10, documents: 1000},
+		{rules: 500, documents: 1000},
+		{rules: 500, documents: 20000},
+	} {
+		instance := benchmarkInstance(size.rules)
+		instance.BuildIndex()
+		data := benchmarkManifests(size.documents, size.rules)
+		b.Run(fmt.Sprintf("rules=%d/documents=%d", size.rules, size.documents), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				_, err := instance.IsVersioned(data)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
 		})
 	}
 }
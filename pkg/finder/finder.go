@@ -0,0 +1,184 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"k8s.io/klog/v2"
+
+	"github.com/danielpickens/lamb/v5/pkg/api"
+)
+
+// Dir is the finder dirlication
+type Dir struct {
+	RootPath string
+	FileList []string
+	Instance *api.Instance
+	// Kustomize renders kustomizations instead of scanning the files inside them
+	Kustomize bool
+	// Kustomizations is the list of kustomization directories found when Kustomize is set
+	Kustomizations []string
+	// HelmCharts renders Helm charts instead of scanning the files inside them
+	HelmCharts bool
+	// Charts is the list of Helm chart directories found when HelmCharts is set
+	Charts []string
+	// HelmValues are values files used to render charts
+	HelmValues []string
+	// HelmSet are values used to render charts, in the form key=value
+	HelmSet []string
+}
+
+// NewFinder returns a new struct with config portions complete.
+func NewFinder(path string, instance *api.Instance) *Dir {
+	cfg := &Dir{
+		Instance: instance,
+	}
+	if path == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		cfg.RootPath = cwd
+	} else {
+		cfg.RootPath = path
+	}
+	return cfg
+}
+
+// FindVersions runs the finder on the Dir.RootPath and returns a list of
+// apiVersions that are deprecated
+func (dir *Dir) FindVersions() error {
+	err := dir.ListFiles()
+	if err != nil {
+		return err
+	}
+	err = dir.scanFiles()
+	if err != nil {
+		return err
+	}
+	for _, chart := range dir.Charts {
+		klog.V(8).Infof("rendering chart: %s", chart)
+		err := dir.scanChart(chart)
+		if err != nil {
+			return fmt.Errorf("error rendering chart '%s'\n   %w", chart, err)
+		}
+	}
+	for _, kustomization := range dir.Kustomizations {
+		klog.V(8).Infof("building kustomization: %s", kustomization)
+		err := dir.scanKustomization(kustomization)
+		if err != nil {
+			return fmt.Errorf("error building kustomization '%s'\n   %w", kustomization, err)
+		}
+	}
+	return nil
+}
+
+// ListFiles gets a list of all the files in the directory and stores it in FileList.
+// When HelmCharts is set, files inside a Helm chart are left out and the chart directory is
+// added to Charts instead. When Kustomize is set, the same applies to kustomization
+// directories and Kustomizations.
+func (dir *Dir) ListFiles() error {
+	var files []string
+	var charts []string
+	var kustomizations []string
+
+	if _, err := os.Stat(dir.RootPath); os.IsNotExist(err) {
+		return fmt.Errorf("specified path does not exist")
+	}
+	err := filepath.Walk(dir.RootPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// subcharts are rendered along with their parent chart, and nested kustomizations
+		// along with the kustomization that contains them
+		inRendered := inDirectories(path, charts) || inDirectories(path, kustomizations)
+		if info.IsDir() {
+			if inRendered {
+				return filepath.SkipDir
+			}
+			if dir.HelmCharts && isChart(path) {
+				charts = append(charts, path)
+			} else if dir.Kustomize && isKustomization(path) {
+				kustomizations = append(kustomizations, path)
+			}
+			return nil
+		}
+		if inRendered {
+			return nil
+		}
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	dir.FileList = files
+	dir.Charts = charts
+	dir.Kustomizations = kustomizations
+	return nil
+}
+
+// inDirectories returns true if any parent directory of path is one of directories.
+// filepath.Walk visits directories before their contents, so parents are already known.
+func inDirectories(path string, directories []string) bool {
+	for parent := filepath.Dir(path); ; parent = filepath.Dir(parent) {
+		if api.StringInSlice(parent, directories) {
+			return true
+		}
+		if next := filepath.Dir(parent); next == parent {
+			return false
+		}
+	}
+}
+
+// scanFiles loops over the file list and attempts to find deprecated versions
+func (dir *Dir) scanFiles() error {
+	for _, file := range dir.FileList {
+		klog.V(8).Infof("processing file: %s", file)
+		apiFile, err := dir.CheckForAPIVersion(file)
+		if err != nil {
+			klog.V(2).Infof("error scanning file %s: %s", file, err.Error())
+		}
+		if apiFile != nil {
+			dir.Instance.Outputs = append(dir.Instance.Outputs, apiFile...)
+		}
+	}
+	return nil
+}
+
+// CheckForAPIVersion checks a filename to see if
+// it is an api-versioned Kubernetes object.
+// Returns the File object if it is.
+func (dir *Dir) CheckForAPIVersion(file string) ([]*api.Output, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	outputs, err := dir.Instance.IsVersioned(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(outputs) < 1 {
+		return nil, nil
+	}
+	for _, output := range outputs {
+		output.FilePath = file
+	}
+	return outputs, nil
+}
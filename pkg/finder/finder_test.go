@@ -0,0 +1,320 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/danielpickens/lamb/v5/pkg/api"
+)
+
+var testInstance = &api.Instance{
+	TargetVersions: map[string]string{
+		"k8s": "v1.16.0",
+	},
+	Components: []string{"k8s"},
+	DeprecatedVersions: []api.Version{
+		{
+			Name:                   "extensions/v1beta1",
+			Kind:                   "Deployment",
+			DeprecatedIn:           "v1.9.0",
+			RemovedIn:              "v1.16.0",
+			ReplacementAPI:         "apps/v1",
+			ReplacementAvailableIn: "v1.9.0",
+			Component:              "k8s",
+		},
+		{
+			Name:                   "extensions/v1beta1",
+			Kind:                   "Ingress",
+			DeprecatedIn:           "v1.14.0",
+			RemovedIn:              "v1.22.0",
+			ReplacementAPI:         "networking.k8s.io/v1",
+			ReplacementAvailableIn: "v1.19.0",
+			Component:              "k8s",
+		},
+	},
+}
+
+func TestDir_ListFiles(t *testing.T) {
+	root := filepath.Join("testdata", "kustomize")
+	tests := []struct {
+		name               string
+		kustomize          bool
+		wantFiles          []string
+		wantKustomizations []string
+	}{
+		{
+			name:      "files",
+			kustomize: false,
+			wantFiles: []string{
+				filepath.Join(root, "base", "deployment.yaml"),
+				filepath.Join(root, "base", "kustomization.yaml"),
+				filepath.Join(root, "overlays", "prod", "kustomization.yaml"),
+				filepath.Join(root, "overlays", "prod", "nested", "kustomization.yaml"),
+				filepath.Join(root, "overlays", "prod", "patches", "api-version.yaml"),
+				filepath.Join(root, "plain.yaml"),
+			},
+		},
+		{
+			name:      "kustomizations",
+			kustomize: true,
+			wantFiles: []string{
+				filepath.Join(root, "plain.yaml"),
+			},
+			// overlays/prod/nested is built as part of overlays/prod, not on its own
+			wantKustomizations: []string{
+				filepath.Join(root, "base"),
+				filepath.Join(root, "overlays", "prod"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := NewFinder(root, testInstance)
+			dir.Kustomize = tt.kustomize
+			err := dir.ListFiles()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantFiles, dir.FileList)
+			assert.Equal(t, tt.wantKustomizations, dir.Kustomizations)
+		})
+	}
+}
+
+func TestDir_ListFiles_charts(t *testing.T) {
+	root := filepath.Join("testdata", "helm")
+	chart := filepath.Join(root, "utilities")
+	tests := []struct {
+		name       string
+		helmCharts bool
+		wantFiles  []string
+		wantCharts []string
+	}{
+		{
+			name:       "files",
+			helmCharts: false,
+			wantFiles: []string{
+				filepath.Join(root, "ingress-values.yaml"),
+				filepath.Join(chart, "Chart.yaml"),
+				filepath.Join(chart, "templates", "NOTES.txt"),
+				filepath.Join(chart, "templates", "_helpers.tpl"),
+				filepath.Join(chart, "templates", "deployment.yaml"),
+				filepath.Join(chart, "templates", "ingress.yaml"),
+				filepath.Join(chart, "values.yaml"),
+			},
+		},
+		{
+			name:       "charts",
+			helmCharts: true,
+			wantFiles:  []string{filepath.Join(root, "ingress-values.yaml")},
+			wantCharts: []string{chart},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := NewFinder(root, testInstance)
+			dir.HelmCharts = tt.helmCharts
+			err := dir.ListFiles()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantFiles, dir.FileList)
+			assert.Equal(t, tt.wantCharts, dir.Charts)
+		})
+	}
+}
+
+func TestDir_scanChart(t *testing.T) {
+	chart := filepath.Join("testdata", "helm", "utilities")
+	tests := []struct {
+		name          string
+		target        string
+		helmValues    []string
+		helmSet       []string
+		wantKinds     []string
+		wantFilePaths []string
+	}{
+		{
+			name:   "nothing deprecated",
+			target: "v1.22.0",
+		},
+		{
+			name:          "kube version from target",
+			target:        "v1.15.0",
+			wantKinds:     []string{"Deployment"},
+			wantFilePaths: []string{filepath.Join(chart, "templates", "deployment.yaml")},
+		},
+		{
+			name:          "values file",
+			target:        "v1.22.0",
+			helmValues:    []string{filepath.Join("testdata", "helm", "ingress-values.yaml")},
+			wantKinds:     []string{"Ingress"},
+			wantFilePaths: []string{filepath.Join(chart, "templates", "ingress.yaml")},
+		},
+		{
+			name:          "set value",
+			target:        "v1.22.0",
+			helmSet:       []string{"ingress.enabled=true"},
+			wantKinds:     []string{"Ingress"},
+			wantFilePaths: []string{filepath.Join(chart, "templates", "ingress.yaml")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := *testInstance
+			instance.Outputs = nil
+			instance.TargetVersions = map[string]string{"k8s": tt.target}
+			dir := NewFinder(chart, &instance)
+			dir.HelmValues = tt.helmValues
+			dir.HelmSet = tt.helmSet
+			err := dir.scanChart(chart)
+			assert.NoError(t, err)
+			var kinds, filePaths []string
+			for _, output := range instance.Outputs {
+				kinds = append(kinds, output.APIVersion.Kind)
+				filePaths = append(filePaths, output.FilePath)
+				assert.Equal(t, "release-name-utilities", output.Name)
+			}
+			assert.Equal(t, tt.wantKinds, kinds)
+			assert.Equal(t, tt.wantFilePaths, filePaths)
+		})
+	}
+}
+
+func Test_chartFilePath(t *testing.T) {
+	assert.Equal(t, filepath.Join("charts", "app", "templates", "deployment.yaml"), chartFilePath(filepath.Join("charts", "app"), "utilities/templates/deployment.yaml"))
+	assert.Equal(t, filepath.Join("app", "charts", "sub", "templates", "service.yaml"), chartFilePath("app", "app/charts/sub/templates/service.yaml"))
+}
+
+func TestDir_FindVersions_chartError(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "templates"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "Chart.yaml"), []byte("apiVersion: v2\nname: broken\nversion: 0.1.0\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "templates", "deployment.yaml"), []byte("{{ .Values.missing.key }}\n"), 0644))
+
+	instance := *testInstance
+	instance.Outputs = nil
+	dir := NewFinder(root, &instance)
+	err := dir.FindVersions()
+	assert.NoError(t, err, "charts are only rendered with HelmCharts")
+
+	dir.HelmCharts = true
+	err = dir.FindVersions()
+	assert.ErrorContains(t, err, "error rendering chart '"+root+"'")
+}
+
+func TestDir_scanKustomization(t *testing.T) {
+	root := filepath.Join("testdata", "kustomize")
+	tests := []struct {
+		name         string
+		path         string
+		wantNames    []string
+		wantFilePath []string
+	}{
+		{
+			name: "base is clean",
+			path: filepath.Join(root, "base"),
+		},
+		{
+			name:         "overlay patches the apiVersion",
+			path:         filepath.Join(root, "overlays", "prod"),
+			wantNames:    []string{"prod-utilities"},
+			wantFilePath: []string{filepath.Join(root, "overlays", "prod") + string(filepath.Separator) + "../../base/deployment.yaml"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := *testInstance
+			instance.Outputs = nil
+			dir := NewFinder(root, &instance)
+			err := dir.scanKustomization(tt.path)
+			assert.NoError(t, err)
+			var names, filePaths []string
+			for _, output := range instance.Outputs {
+				names = append(names, output.Name)
+				filePaths = append(filePaths, output.FilePath)
+			}
+			assert.Equal(t, tt.wantNames, names)
+			assert.Equal(t, tt.wantFilePath, filePaths)
+		})
+	}
+}
+
+func TestDir_FindVersions_kustomizationError(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "kustomization.yaml"), []byte("resources:\n- missing.yaml\n"), 0644))
+
+	instance := *testInstance
+	instance.Outputs = nil
+	dir := NewFinder(root, &instance)
+	dir.Kustomize = true
+	err := dir.FindVersions()
+	assert.ErrorContains(t, err, "error building kustomization '"+root+"'")
+}
+
+func Test_prepareKustomization(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "origin annotations added",
+			data: "resources:\n- deployment.yaml\n",
+			want: "buildMetadata:\n    - originAnnotations\nresources:\n    - deployment.yaml\n",
+		},
+		{
+			name: "origin annotations already set",
+			data: "buildMetadata: [originAnnotations]\nresources:\n- deployment.yaml\n",
+			want: "buildMetadata: [originAnnotations]\nresources:\n- deployment.yaml\n",
+		},
+		{
+			name:    "remote resource",
+			data:    "resources:\n- https://github.com/example/repo//deploy?ref=v1.0.0\n",
+			wantErr: true,
+		},
+		{
+			name:    "remote base",
+			data:    "bases:\n- github.com/example/repo/deploy\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid yaml",
+			data:    "resources: [",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := prepareKustomization("kustomization.yaml", []byte(tt.data))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func Test_inDirectories(t *testing.T) {
+	directories := []string{filepath.Join("deploy", "base")}
+	assert.True(t, inDirectories(filepath.Join("deploy", "base", "deployment.yaml"), directories))
+	assert.True(t, inDirectories(filepath.Join("deploy", "base", "nested", "service.yaml"), directories))
+	assert.False(t, inDirectories(filepath.Join("deploy", "other", "deployment.yaml"), directories))
+	assert.False(t, inDirectories(filepath.Join("deploy", "base.yaml"), directories))
+}
@@ -0,0 +1,138 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/getter"
+	"k8s.io/klog/v2"
+
+	"github.com/danielpickens/lamb/v5/pkg/api"
+)
+
+const (
+	helmReleaseName      = "release-name"
+	helmReleaseNamespace = "default"
+)
+
+// isChart returns true if the directory contains a Chart.yaml
+func isChart(path string) bool {
+	info, err := os.Stat(filepath.Join(path, chartutil.ChartfileName))
+	return err == nil && !info.IsDir()
+}
+
+// scanChart renders the chart in path the way helm template would and checks every
+// rendered manifest. The FilePath of each output is the template that produced it.
+func (dir *Dir) scanChart(path string) error {
+	chart, err := loader.Load(path)
+	if err != nil {
+		return err
+	}
+	options := values.Options{
+		ValueFiles: dir.HelmValues,
+		Values:     dir.HelmSet,
+	}
+	// no getters, so values files are only ever read from disk
+	vals, err := options.MergeValues(getter.Providers{})
+	if err != nil {
+		return err
+	}
+	err = chartutil.ProcessDependenciesWithMerge(chart, vals)
+	if err != nil {
+		return err
+	}
+	releaseOptions := chartutil.ReleaseOptions{
+		Name:      helmReleaseName,
+		Namespace: helmReleaseNamespace,
+		IsInstall: true,
+	}
+	renderValues, err := chartutil.ToRenderValues(chart, vals, releaseOptions, dir.capabilities())
+	if err != nil {
+		return err
+	}
+	rendered, err := engine.Render(chart, renderValues)
+	if err != nil {
+		return err
+	}
+
+	templates := make([]string, 0, len(rendered))
+	for name := range rendered {
+		templates = append(templates, name)
+	}
+	sort.Strings(templates)
+	for _, name := range templates {
+		if !isManifestTemplate(name) || strings.TrimSpace(rendered[name]) == "" {
+			continue
+		}
+		outputs, err := dir.Instance.IsVersioned([]byte(rendered[name]))
+		if err != nil {
+			klog.V(2).Infof("error scanning template %s: %s", name, err.Error())
+			continue
+		}
+		filePath := chartFilePath(path, name)
+		for _, output := range outputs {
+			output.FilePath = filePath
+			// positions refer to the rendered template, not to the file on disk
+			output.Document = 0
+			output.Line = 0
+			output.Column = 0
+		}
+		dir.Instance.Outputs = append(dir.Instance.Outputs, outputs...)
+	}
+	return nil
+}
+
+// capabilities returns the helm capabilities with the kube version set to the k8s target version
+func (dir *Dir) capabilities() *chartutil.Capabilities {
+	caps := chartutil.DefaultCapabilities.Copy()
+	target, ok := dir.Instance.TargetVersions["k8s"]
+	if !ok {
+		return caps
+	}
+	if normalized, ok := api.NormalizeVersion(target); ok {
+		target = normalized
+	}
+	kubeVersion, err := chartutil.ParseKubeVersion(target)
+	if err != nil {
+		klog.V(3).Infof("could not use k8s target version %s as the kube version: %s", target, err.Error())
+		return caps
+	}
+	caps.KubeVersion = *kubeVersion
+	return caps
+}
+
+// isManifestTemplate returns false for rendered files that never contain manifests
+func isManifestTemplate(name string) bool {
+	return strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".json")
+}
+
+// chartFilePath turns a rendered template name such as mychart/templates/deployment.yaml
+// into the path of the template on disk. The first element of the name is the chart name,
+// which need not match the directory.
+func chartFilePath(path string, name string) string {
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) < 2 {
+		return path
+	}
+	return filepath.Join(path, filepath.FromSlash(parts[1]))
+}
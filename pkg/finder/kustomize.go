@@ -0,0 +1,143 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/resource"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+
+	"github.com/danielpickens/lamb/v5/pkg/api"
+)
+
+// isKustomization returns true if the directory contains a kustomization file
+func isKustomization(path string) bool {
+	for _, name := range konfig.RecognizedKustomizationFileNames() {
+		info, err := os.Stat(filepath.Join(path, name))
+		if err == nil && !info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// scanKustomization builds the kustomization in path and checks every rendered resource.
+// The FilePath of each output is the kustomization directory joined with the file the
+// resource came from, so overlays can be told apart.
+func (dir *Dir) scanKustomization(path string) error {
+	options := krusty.MakeDefaultOptions()
+	options.PluginConfig = types.DisabledPluginConfig()
+	kustomizer := krusty.MakeKustomizer(options)
+	resMap, err := kustomizer.Run(localFileSystem{filesys.MakeFsOnDisk()}, path)
+	if err != nil {
+		return err
+	}
+	for _, res := range resMap.Resources() {
+		data, err := res.AsYAML()
+		if err != nil {
+			return err
+		}
+		outputs, err := dir.Instance.IsVersioned(data)
+		if err != nil {
+			return err
+		}
+		filePath := kustomizeFilePath(path, res)
+		for _, output := range outputs {
+			output.FilePath = filePath
+			// positions refer to the rendered resource, not to a file on disk
+			output.Document = 0
+			output.Line = 0
+			output.Column = 0
+		}
+		dir.Instance.Outputs = append(dir.Instance.Outputs, outputs...)
+	}
+	return nil
+}
+
+// kustomizeFilePath returns the path of the file a rendered resource originates from
+func kustomizeFilePath(path string, res *resource.Resource) string {
+	origin, err := res.GetOrigin()
+	if err != nil {
+		klog.V(3).Infof("could not read origin of %s in %s: %s", res.GetName(), path, err.Error())
+	}
+	if origin != nil {
+		if origin.Path != "" {
+			return path + string(filepath.Separator) + origin.Path
+		}
+		if origin.ConfiguredIn != "" {
+			return path + string(filepath.Separator) + origin.ConfiguredIn
+		}
+	}
+	return path
+}
+
+// localFileSystem reads kustomizations from disk, turning on origin annotations so that
+// resources can be traced back to their files, and refusing remote resources so that a
+// build never reaches the network
+type localFileSystem struct {
+	filesys.FileSystem
+}
+
+// ReadFile implements filesys.FileSystem
+func (fs localFileSystem) ReadFile(path string) ([]byte, error) {
+	data, err := fs.FileSystem.ReadFile(path)
+	if err != nil || !api.StringInSlice(filepath.Base(path), konfig.RecognizedKustomizationFileNames()) {
+		return data, err
+	}
+	return prepareKustomization(path, data)
+}
+
+func prepareKustomization(path string, data []byte) ([]byte, error) {
+	kustomization := map[string]interface{}{}
+	err := yaml.Unmarshal(data, &kustomization)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+	for _, field := range []string{"resources", "bases", "components"} {
+		entries, _ := kustomization[field].([]interface{})
+		for _, entry := range entries {
+			if ref, ok := entry.(string); ok && isRemote(ref) {
+				return nil, fmt.Errorf("remote %s are not supported in %s: %s", field, path, ref)
+			}
+		}
+	}
+	buildMetadata, _ := kustomization["buildMetadata"].([]interface{})
+	for _, option := range buildMetadata {
+		if option == types.OriginAnnotations {
+			return data, nil
+		}
+	}
+	kustomization["buildMetadata"] = append(buildMetadata, types.OriginAnnotations)
+	return yaml.Marshal(kustomization)
+}
+
+// isRemote returns true for resource references that kustomize would fetch over the network
+func isRemote(ref string) bool {
+	for _, prefix := range []string{"http://", "https://", "git::", "git@", "ssh://", "github.com/", "gitlab.com/", "bitbucket.org/"} {
+		if strings.HasPrefix(ref, prefix) {
+			return true
+		}
+	}
+	return strings.Contains(ref, "?ref=")
+}
@@ -0,0 +1,7 @@
+{{- define "utilities.deploymentAPIVersion" -}}
+{{- if semverCompare "<1.16-0" .Capabilities.KubeVersion.Version -}}
+extensions/v1beta1
+{{- else -}}
+apps/v1
+{{- end -}}
+{{- end -}}
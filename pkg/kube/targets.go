@@ -0,0 +1,127 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kube
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"golang.org/x/mod/semver"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"github.com/danielpickens/lamb/v5/pkg/api"
+)
+
+// InferredVersion is the target version of a component read from a cluster
+type InferredVersion struct {
+	Component string
+	Version   string
+	// Source describes where the version was read from
+	Source string
+}
+
+// ServerTargetVersion returns the k8s target version of the cluster, from the version
+// reported by the API server
+func ServerTargetVersion(client kubernetes.Interface) (*InferredVersion, error) {
+	info, err := client.Discovery().ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("could not get the server version: %w", err)
+	}
+	if !api.IsValidVersion(info.GitVersion) {
+		return nil, fmt.Errorf("server version %s is not a valid version", info.GitVersion)
+	}
+	return &InferredVersion{
+		Component: "k8s",
+		Version:   info.GitVersion,
+		Source:    "server version",
+	}, nil
+}
+
+// ProbeComponentVersions returns the target versions of the components in probes that are
+// running in the cluster. A component that none of its probes find is left out, as is a
+// probe that fails, which is only logged.
+func ProbeComponentVersions(client kubernetes.Interface, probes api.ComponentProbes) []InferredVersion {
+	components := make([]string, 0, len(probes))
+	for component := range probes {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+
+	var inferred []InferredVersion
+	for _, component := range components {
+		for _, probe := range probes[component] {
+			version, err := probeComponentVersion(client, component, probe)
+			if err != nil {
+				klog.Warningf("could not probe the version of %s: %s", component, err.Error())
+				continue
+			}
+			if version != nil {
+				inferred = append(inferred, *version)
+				break
+			}
+		}
+	}
+	return inferred
+}
+
+// probeComponentVersion returns the lowest version in the image tags of the Deployments
+// matching probe, or nil if there are none
+func probeComponentVersion(client kubernetes.Interface, component string, probe api.ComponentProbe) (*InferredVersion, error) {
+	deployments, err := client.AppsV1().Deployments(probe.Namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: probe.Selector,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var inferred *InferredVersion
+	for _, deployment := range deployments.Items {
+		image, found := deploymentImage(deployment, probe.Container)
+		if !found {
+			continue
+		}
+		version, ok := api.ImageTagVersion(image)
+		if !ok {
+			klog.V(2).Infof("image %s of deployment %s/%s has no version tag", image, deployment.Namespace, deployment.Name)
+			continue
+		}
+		if inferred != nil && inferred.Version != version {
+			klog.Warningf("found %s %s and %s, using the lower version", component, inferred.Version, version)
+			if semver.Compare(inferred.Version, version) < 0 {
+				continue
+			}
+		}
+		inferred = &InferredVersion{
+			Component: component,
+			Version:   version,
+			Source:    fmt.Sprintf("image %s of deployment %s/%s", image, deployment.Namespace, deployment.Name),
+		}
+	}
+	return inferred, nil
+}
+
+// deploymentImage returns the image of the named container of a deployment, or the first
+// container if name is empty
+func deploymentImage(deployment appsv1.Deployment, name string) (string, bool) {
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		if name == "" || container.Name == name {
+			return container.Image, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 danielpickens
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kube
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/danielpickens/lamb/v5/pkg/api"
+)
+
+func deployment(namespace string, name string, labels map[string]string, containers ...corev1.Container) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: containers},
+			},
+		},
+	}
+}
+
+func TestServerTargetVersion(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: "v1.27.3-eks-2d98532"}
+
+	got, err := ServerTargetVersion(client)
+	assert.NoError(t, err)
+	assert.Equal(t, &InferredVersion{Component: "k8s", Version: "v1.27.3-eks-2d98532", Source: "server version"}, got)
+
+	client.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: "unknown"}
+	_, err = ServerTargetVersion(client)
+	assert.EqualError(t, err, "server version unknown is not a valid version")
+}
+
+func TestProbeComponentVersions(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		deployment("istio-system", "istiod", map[string]string{"app": "istiod"},
+			corev1.Container{Name: "discovery", Image: "docker.io/istio/pilot:1.20.1-distroless"},
+		),
+		deployment("istio-canary", "istiod-canary", map[string]string{"app": "istiod"},
+			corev1.Container{Name: "discovery", Image: "docker.io/istio/pilot:1.21.0"},
+		),
+		deployment("cert-manager", "cert-manager", map[string]string{"app.kubernetes.io/name": "cert-manager"},
+			corev1.Container{Name: "sidecar", Image: "example.com/sidecar:v9.9.9"},
+			corev1.Container{Name: "cert-manager-controller", Image: "quay.io/jetstack/cert-manager-controller:v1.13.2"},
+		),
+		deployment("default", "latest", map[string]string{"app": "latest"},
+			corev1.Container{Name: "app", Image: "example.com/app:latest"},
+		),
+	)
+
+	got := ProbeComponentVersions(client, api.ComponentProbes{
+		"istio": {
+			{Namespace: "istio", Selector: "app=istiod"},
+			{Selector: "app=istiod", Container: "discovery"},
+		},
+		"cert-manager": {{Namespace: "cert-manager", Selector: "app.kubernetes.io/name=cert-manager", Container: "cert-manager-controller"}},
+		"latest":       {{Selector: "app=latest"}},
+		"missing":      {{Selector: "app=missing"}},
+	})
+	assert.Equal(t, []InferredVersion{
+		{Component: "cert-manager", Version: "v1.13.2", Source: "image quay.io/jetstack/cert-manager-controller:v1.13.2 of deployment cert-manager/cert-manager"},
+		{Component: "istio", Version: "v1.20.1", Source: "image docker.io/istio/pilot:1.20.1-distroless of deployment istio-system/istiod"},
+	}, got)
+}